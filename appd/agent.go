@@ -0,0 +1,204 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+)
+
+// Agent describes a language agent that the buildpack can contribute.
+type Agent interface {
+
+	// PlanName is the name of the build plan entry provided and required by the agent.
+	PlanName() string
+
+	// RequiredPlan is the name of the build plan entry, provided by another buildpack, that selects the agent.
+	RequiredPlan() string
+
+	// DependencyID is the id of the buildpack dependency containing the agent.
+	DependencyID() string
+
+	// Layers returns the layer contributors and BOM entries for the agent.
+	Layers(context AgentContext) ([]libcnb.LayerContributor, []libcnb.BOMEntry, error)
+}
+
+// AgentContext contains the values needed by an Agent to create its layers.
+type AgentContext struct {
+	Build                 libcnb.BuildContext
+	ConfigurationResolver libpak.ConfigurationResolver
+	Dependency            libpak.BuildpackDependency
+	DependencyCache       libpak.DependencyCache
+	Logger                bard.Logger
+	Provenance            *Provenance
+}
+
+// Agents is the registry of agents used by Detect and Build when none are configured explicitly.
+var Agents = []Agent{Java{}, PHP{}}
+
+// Java is the Agent for JVM applications.
+type Java struct{}
+
+func (Java) PlanName() string {
+	return "appdynamics-java"
+}
+
+func (Java) RequiredPlan() string {
+	return "jvm-application"
+}
+
+func (Java) DependencyID() string {
+	return "appdynamics-java"
+}
+
+func (j Java) Layers(context AgentContext) ([]libcnb.LayerContributor, []libcnb.BOMEntry, error) {
+	dep, err := j.CustomAgentDependency(context)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create custom agent dependency\n%w", err)
+	} else if dep != nil {
+		context.Logger.Bodyf("Using custom agent from %s", dep.URI)
+		context.Dependency = *dep
+	}
+
+	var externalConfigurationDependency *libpak.BuildpackDependency
+	if uri, ok := context.ConfigurationResolver.Resolve("BP_APPD_EXT_CONF_URI"); ok {
+		v, _ := context.ConfigurationResolver.Resolve("BP_APPD_EXT_CONF_VERSION")
+		s, _ := context.ConfigurationResolver.Resolve("BP_APPD_EXT_CONF_SHA256")
+
+		externalConfigurationDependency = &libpak.BuildpackDependency{
+			ID:      "appdynamics-external-configuration",
+			Name:    "AppDynamics External Configuration",
+			Version: v,
+			URI:     uri,
+			SHA256:  s,
+			Stacks:  []string{context.Build.StackID},
+			CPEs:    []string{fmt.Sprintf("cpe:2.3:a:appdynamics:external-configuration:%s:*:*:*:*:*:*:*", v)},
+			PURL:    fmt.Sprintf("pkg:generic/appdynamics-external-configuration@%s", v),
+		}
+	}
+
+	ja, be := NewJavaAgent(context.Dependency, context.ConfigurationResolver, context.DependencyCache)
+	ja.Logger = context.Logger
+
+	jc, bes := NewJavaConfiguration(context.Build.Buildpack.Path, context.Dependency, context.ConfigurationResolver,
+		externalConfigurationDependency, context.DependencyCache)
+	jc.Logger = context.Logger
+
+	if externalConfigurationDependency != nil {
+		if jc.ExternalConfigurationCredentials, err = ResolveExternalConfigurationCredentials(context.Build.Platform.Bindings,
+			context.ConfigurationResolver); err != nil {
+			return nil, nil, fmt.Errorf("unable to resolve external configuration credentials\n%w", err)
+		}
+
+		if jc.SignatureVerifier, err = ResolveSignatureVerifier(context.Build.Platform.Bindings); err != nil {
+			return nil, nil, fmt.Errorf("unable to resolve external configuration signature verifier\n%w", err)
+		}
+	}
+
+	layers := []libcnb.LayerContributor{ja, jc}
+	if p := context.Provenance.ForAgent(context.Dependency); p != nil {
+		pl := NewProvenanceLayer(*p)
+		pl.Logger = context.Logger
+		layers = append(layers, pl)
+	}
+
+	return layers, append([]libcnb.BOMEntry{be}, bes...), nil
+}
+
+// CustomAgentDependency returns a dependency for an agent distribution configured with $BP_APPD_JAVA_AGENT_URI or
+// $BP_APPD_JAVA_AGENT_PATH, or nil if neither is set. The SHA256 of an archive in the application is always computed,
+// while a downloaded archive requires $BP_APPD_JAVA_AGENT_SHA256, as the cached agent layer is keyed on it. Both
+// require $BP_APPD_JAVA_AGENT_VERSION.
+func (Java) CustomAgentDependency(context AgentContext) (*libpak.BuildpackDependency, error) {
+	uri, uriOk := context.ConfigurationResolver.Resolve("BP_APPD_JAVA_AGENT_URI")
+	path, pathOk := context.ConfigurationResolver.Resolve("BP_APPD_JAVA_AGENT_PATH")
+	s, _ := context.ConfigurationResolver.Resolve("BP_APPD_JAVA_AGENT_SHA256")
+	v, _ := context.ConfigurationResolver.Resolve("BP_APPD_JAVA_AGENT_VERSION")
+
+	switch {
+	case uriOk && pathOk:
+		return nil, fmt.Errorf("only one of $BP_APPD_JAVA_AGENT_URI and $BP_APPD_JAVA_AGENT_PATH may be set")
+	case !uriOk && !pathOk:
+		return nil, nil
+	case v == "":
+		return nil, fmt.Errorf("$BP_APPD_JAVA_AGENT_VERSION must be set with a custom Java agent")
+	case uriOk && s == "":
+		return nil, fmt.Errorf("$BP_APPD_JAVA_AGENT_SHA256 must be set with $BP_APPD_JAVA_AGENT_URI")
+	case pathOk:
+		file := filepath.Join(context.Build.Application.Path, path)
+
+		in, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %s\n%w", file, err)
+		}
+		defer in.Close()
+
+		h := sha256.New()
+		if _, err := io.Copy(h, in); err != nil {
+			return nil, fmt.Errorf("unable to compute SHA256 of %s\n%w", file, err)
+		}
+
+		actual := hex.EncodeToString(h.Sum(nil))
+		if s != "" && s != actual {
+			return nil, fmt.Errorf("sha256 for %s %s does not match expected %s", file, actual, s)
+		}
+
+		uri, s = (&url.URL{Scheme: "file", Path: file}).String(), actual
+	}
+
+	return &libpak.BuildpackDependency{
+		ID:      "appdynamics-java",
+		Name:    "AppDynamics Java Agent",
+		Version: v,
+		URI:     uri,
+		SHA256:  s,
+		Stacks:  []string{context.Build.StackID},
+		CPEs:    []string{fmt.Sprintf("cpe:2.3:a:appdynamics:java-agent:%s:*:*:*:*:*:*:*", v)},
+		PURL:    fmt.Sprintf("pkg:generic/appdynamics-java-agent@%s", v),
+	}, nil
+}
+
+// PHP is the Agent for PHP applications.
+type PHP struct{}
+
+func (PHP) PlanName() string {
+	return "appdynamics-php"
+}
+
+func (PHP) RequiredPlan() string {
+	return "php"
+}
+
+func (PHP) DependencyID() string {
+	return "appdynamics-php"
+}
+
+func (PHP) Layers(context AgentContext) ([]libcnb.LayerContributor, []libcnb.BOMEntry, error) {
+	pa, be := NewPHPAgent(context.Dependency, context.ConfigurationResolver, context.DependencyCache)
+	pa.Logger = context.Logger
+
+	return []libcnb.LayerContributor{pa}, []libcnb.BOMEntry{be}, nil
+}
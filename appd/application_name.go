@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/heroku/color"
+	"github.com/paketo-buildpacks/libpak/bard"
+)
+
+var gradleRootProjectName = regexp.MustCompile(`rootProject\.name\s*=\s*["']([^"']+)["']`)
+
+// DefaultApplicationName returns a name for the application and the file it was derived from. The name is read from,
+// in order, project.toml, the artifactId of pom.xml, the rootProject.name of the Gradle settings, and the name of
+// composer.json. A file that cannot be read is logged and skipped, as the name is only a default. Returns empty strings
+// if none of them contain a name.
+func DefaultApplicationName(applicationPath string, logger bard.Logger) (string, string) {
+	for _, f := range []struct {
+		name string
+		read func(string) (string, error)
+	}{
+		{"project.toml", projectTOMLName},
+		{"pom.xml", pomName},
+		{"settings.gradle", gradleName},
+		{"settings.gradle.kts", gradleName},
+		{"composer.json", composerName},
+	} {
+		file := filepath.Join(applicationPath, f.name)
+
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+
+		s, err := f.read(file)
+		if err != nil {
+			logger.Bodyf("%s unable to read application name from %s: %s",
+				color.YellowString("Warning:"), f.name, strings.ReplaceAll(err.Error(), "\n", ": "))
+			continue
+		}
+
+		if s = strings.TrimSpace(s); s != "" {
+			return s, f.name
+		}
+	}
+
+	return "", ""
+}
+
+func projectTOMLName(file string) (string, error) {
+	var p struct {
+		Metadata struct {
+			Name string `toml:"name"`
+		} `toml:"_"`
+		Project struct {
+			Name string `toml:"name"`
+		} `toml:"project"`
+	}
+
+	if _, err := toml.DecodeFile(file, &p); err != nil {
+		return "", fmt.Errorf("unable to decode %s\n%w", file, err)
+	}
+
+	if p.Metadata.Name != "" {
+		return p.Metadata.Name, nil
+	}
+
+	return p.Project.Name, nil
+}
+
+func pomName(file string) (string, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	var p struct {
+		ArtifactID string `xml:"artifactId"`
+	}
+
+	if err := xml.Unmarshal(b, &p); err != nil {
+		return "", fmt.Errorf("unable to decode %s\n%w", file, err)
+	}
+
+	return p.ArtifactID, nil
+}
+
+func gradleName(file string) (string, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	if m := gradleRootProjectName.FindSubmatch(b); m != nil {
+		return string(m[1]), nil
+	}
+
+	return "", nil
+}
+
+func composerName(file string) (string, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	var c struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.Unmarshal(b, &c); err != nil {
+		return "", fmt.Errorf("unable to decode %s\n%w", file, err)
+	}
+
+	return c.Name, nil
+}
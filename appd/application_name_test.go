@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/appd"
+)
+
+func testApplicationName(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		buffer *bytes.Buffer
+		path   string
+	)
+
+	it.Before(func() {
+		buffer = bytes.NewBuffer(nil)
+		path = t.TempDir()
+	})
+
+	expectName := func(name string, source string) {
+		n, s := appd.DefaultApplicationName(path, bard.NewLogger(buffer))
+		Expect(n).To(Equal(name))
+		Expect(s).To(Equal(source))
+	}
+
+	it("returns empty name without build metadata", func() {
+		expectName("", "")
+	})
+
+	it("reads project.toml schema 0.2", func() {
+		Expect(ioutil.WriteFile(filepath.Join(path, "project.toml"), []byte(`[_]
+schema-version = "0.2"
+name = "test-project"
+`), 0644)).To(Succeed())
+
+		expectName("test-project", "project.toml")
+	})
+
+	it("reads project.toml schema 0.1", func() {
+		Expect(ioutil.WriteFile(filepath.Join(path, "project.toml"), []byte(`[project]
+name = "test-project"
+`), 0644)).To(Succeed())
+
+		expectName("test-project", "project.toml")
+	})
+
+	it("reads pom.xml artifactId", func() {
+		Expect(ioutil.WriteFile(filepath.Join(path, "pom.xml"), []byte(`<project>
+  <parent><artifactId>test-parent</artifactId></parent>
+  <artifactId>test-artifact</artifactId>
+</project>
+`), 0644)).To(Succeed())
+
+		expectName("test-artifact", "pom.xml")
+	})
+
+	it("reads Gradle root project name", func() {
+		Expect(ioutil.WriteFile(filepath.Join(path, "settings.gradle.kts"), []byte(`rootProject.name = "test-gradle"
+include("test-module")
+`), 0644)).To(Succeed())
+
+		expectName("test-gradle", "settings.gradle.kts")
+	})
+
+	it("reads composer.json name", func() {
+		Expect(ioutil.WriteFile(filepath.Join(path, "composer.json"), []byte(`{"name": "test-vendor/test-package"}`), 0644)).
+			To(Succeed())
+
+		expectName("test-vendor/test-package", "composer.json")
+	})
+
+	it("prefers project.toml", func() {
+		Expect(ioutil.WriteFile(filepath.Join(path, "project.toml"), []byte(`[_]
+name = "test-project"
+`), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(path, "pom.xml"), []byte(`<project><artifactId>test-artifact</artifactId></project>`), 0644)).
+			To(Succeed())
+
+		expectName("test-project", "project.toml")
+	})
+
+	it("falls through project.toml without name", func() {
+		Expect(ioutil.WriteFile(filepath.Join(path, "project.toml"), []byte(`[_]
+schema-version = "0.2"
+`), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(path, "pom.xml"), []byte(`<project><artifactId>test-artifact</artifactId></project>`), 0644)).
+			To(Succeed())
+
+		expectName("test-artifact", "pom.xml")
+	})
+
+	it("skips files that cannot be decoded", func() {
+		Expect(ioutil.WriteFile(filepath.Join(path, "project.toml"), []byte("[_\n"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(path, "pom.xml"), []byte(`<?xml version="1.0" encoding="ISO-8859-1"?>
+<project><artifactId>test-artifact</artifactId></project>`), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(path, "composer.json"), []byte(`{"name": "test/composer"}`), 0644)).To(Succeed())
+
+		expectName("test/composer", "composer.json")
+		Expect(buffer.String()).To(ContainSubstring("unable to read application name from project.toml"))
+		Expect(buffer.String()).To(ContainSubstring("unable to read application name from pom.xml"))
+	})
+
+	it("returns empty name if no file can be decoded", func() {
+		Expect(ioutil.WriteFile(filepath.Join(path, "composer.json"), []byte(`{"name":`), 0644)).To(Succeed())
+
+		expectName("", "")
+		Expect(buffer.String()).To(ContainSubstring("unable to read application name from composer.json"))
+	})
+}
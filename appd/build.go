@@ -18,13 +18,20 @@ package appd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/paketo-buildpacks/libpak"
 	"github.com/paketo-buildpacks/libpak/bard"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/helper"
 )
 
 type Build struct {
+	Agents []Agent
 	Logger bard.Logger
 }
 
@@ -32,7 +39,7 @@ func (b Build) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
 	b.Logger.Title(context.Buildpack)
 	result := libcnb.NewBuildResult()
 
-	cr, err := libpak.NewConfigurationResolver(context.Buildpack, &b.Logger)
+	cr, err := newConfigurationResolver(context.Buildpack, &b.Logger)
 	if err != nil {
 		return libcnb.BuildResult{}, fmt.Errorf("unable to create configuration resolver\n%w", err)
 	}
@@ -50,57 +57,123 @@ func (b Build) Build(context libcnb.BuildContext) (libcnb.BuildResult, error) {
 	}
 	dc.Logger = b.Logger
 
-	if _, ok, err := pr.Resolve("appdynamics-java"); err != nil {
-		return libcnb.BuildResult{}, fmt.Errorf("unable to resolve appdynamics-java plan entry\n%w", err)
-	} else if ok {
-		agentDependency, err := dr.Resolve("appdynamics-java", "")
-		if err != nil {
-			return libcnb.BuildResult{}, fmt.Errorf("unable to find dependency\n%w", err)
-		}
+	defaults := map[string]string{}
 
-		var externalConfigurationDependency *libpak.BuildpackDependency
-		if uri, ok := cr.Resolve("BP_APPD_EXT_CONF_URI"); ok {
-			v, _ := cr.Resolve("BP_APPD_EXT_CONF_VERSION")
-			s, _ := cr.Resolve("BP_APPD_EXT_CONF_SHA256")
-
-			externalConfigurationDependency = &libpak.BuildpackDependency{
-				ID:      "appdynamics-external-configuration",
-				Name:    "AppDynamics External Configuration",
-				Version: v,
-				URI:     uri,
-				SHA256:  s,
-				Stacks:  []string{context.StackID},
-				CPEs:    []string{fmt.Sprintf("cpe:2.3:a:appdynamics:external-configuration:%s:*:*:*:*:*:*:*", v)},
-				PURL:    fmt.Sprintf("pkg:generic/appdynamics-external-configuration@%s", v),
-			}
-		}
+	if name, source := DefaultApplicationName(context.Application.Path, b.Logger); name != "" {
+		b.Logger.Bodyf("Defaulting application and tier names to %s from %s", name, source)
+		defaults["APPDYNAMICS_AGENT_APPLICATION_NAME"] = name
+		defaults["APPDYNAMICS_AGENT_TIER_NAME"] = name
+	}
 
-		ja, bes := NewJavaAgent(context.Buildpack.Path, agentDependency, cr, externalConfigurationDependency, dc)
-		ja.Logger = b.Logger
-		result.Layers = append(result.Layers, ja)
-		for _, be := range bes {
-			result.BOM.Entries = append(result.BOM.Entries, be)
+	binding, bound, err := helper.ResolveBinding(context.Platform.Bindings, "BP_APPD_BINDING_NAME")
+	if err != nil {
+		return libcnb.BuildResult{}, fmt.Errorf("unable to resolve binding AppDynamics\n%w", err)
+	}
+
+	if bound && cr.ResolveBool("BP_APPD_BINDING_DEFAULTS") {
+		var names []string
+		for k, v := range BindingDefaults(binding) {
+			defaults[k] = v
+			names = append(names, k)
 		}
+		sort.Strings(names)
+		b.Logger.Bodyf("Defaulting %s from binding %s", strings.Join(names, ", "), binding.Name)
 	}
 
-	if _, ok, err := pr.Resolve("appdynamics-php"); err != nil {
-		return libcnb.BuildResult{}, fmt.Errorf("unable to resolve appdynamics-php plan entry\n%w", err)
-	} else if ok {
-		dep, err := dr.Resolve("appdynamics-php", "")
+	deactivate := !bound && cr.ResolveBool("BP_APPD_ENABLED")
+	if deactivate {
+		b.Logger.Body("Deactivating agent at launch unless a binding or controller host name is configured")
+	}
+
+	provenance, err := NewProvenance(context, cr)
+	if err != nil {
+		return libcnb.BuildResult{}, fmt.Errorf("unable to determine build provenance\n%w", err)
+	}
+	if provenance.Revision != "" {
+		b.Logger.Bodyf("Recording source revision %s", provenance.Revision)
+	}
+
+	for _, a := range b.agents() {
+		if _, ok, err := pr.Resolve(a.PlanName()); err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to resolve %s plan entry\n%w", a.PlanName(), err)
+		} else if !ok {
+			continue
+		}
+
+		dep, err := dr.Resolve(a.DependencyID(), "")
 		if err != nil {
 			return libcnb.BuildResult{}, fmt.Errorf("unable to find dependency\n%w", err)
 		}
 
-		pa, be := NewPHPAgent(dep, dc)
-		pa.Logger = b.Logger
-		result.Layers = append(result.Layers, pa)
-		result.BOM.Entries = append(result.BOM.Entries, be)
+		layers, bes, err := a.Layers(AgentContext{
+			Build:                 context,
+			ConfigurationResolver: cr,
+			Dependency:            dep,
+			DependencyCache:       dc,
+			Logger:                b.Logger,
+			Provenance:            &provenance,
+		})
+		if err != nil {
+			return libcnb.BuildResult{}, fmt.Errorf("unable to create %s layers\n%w", a.PlanName(), err)
+		}
+
+		result.Layers = append(result.Layers, layers...)
+		result.BOM.Entries = append(result.BOM.Entries, bes...)
 	}
 
-	h, be := libpak.NewHelperLayer(context.Buildpack, "properties")
+	if len(result.Layers) > 0 && (len(defaults) > 0 || deactivate) {
+		d := NewLaunchDefaults(defaults, deactivate)
+		d.Logger = b.Logger
+		result.Layers = append(result.Layers, d)
+	}
+
+	h, be := libpak.NewHelperLayer(context.Buildpack, "properties", "preflight", "opentelemetry", "provenance", "unique-host-id")
 	h.Logger = b.Logger
 	result.Layers = append(result.Layers, h)
 	result.BOM.Entries = append(result.BOM.Entries, be)
 
+	b.logSummary(context.Layers.Path, result)
+
 	return result, nil
 }
+
+// logSummary logs the dependencies and layers contributed by the build so that they can be compared with the effective
+// configuration logged by the helper at launch.
+func (b Build) logSummary(layersPath string, result libcnb.BuildResult) {
+	b.Logger.Header("AppDynamics build summary")
+
+	for _, e := range result.BOM.Entries {
+		if e.Name == "helper" {
+			continue
+		}
+
+		b.Logger.Bodyf("%s %v from %v", e.Metadata["name"], e.Metadata["version"], e.Metadata["uri"])
+	}
+
+	for _, l := range result.Layers {
+		b.Logger.Bodyf("Layer %s", filepath.Join(layersPath, l.Name()))
+	}
+}
+
+// newConfigurationResolver creates a libpak.ConfigurationResolver that logs the values of SensitiveConfigurations
+// masked. The values are masked in the environment only while the configuration is logged.
+func newConfigurationResolver(buildpack libcnb.Buildpack, logger *bard.Logger) (libpak.ConfigurationResolver, error) {
+	for _, name := range SensitiveConfigurations {
+		if v, ok := os.LookupEnv(name); ok {
+			if err := os.Setenv(name, "********"); err != nil {
+				return libpak.ConfigurationResolver{}, fmt.Errorf("unable to mask $%s\n%w", name, err)
+			}
+			defer os.Setenv(name, v)
+		}
+	}
+
+	return libpak.NewConfigurationResolver(buildpack, logger)
+}
+
+func (b Build) agents() []Agent {
+	if b.Agents == nil {
+		return Agents
+	}
+
+	return b.Agents
+}
@@ -17,12 +17,16 @@
 package appd_test
 
 import (
+	"bytes"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/buildpacks/libcnb"
 	. "github.com/onsi/gomega"
 	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/sclevine/spec"
 
 	"github.com/paketo-buildpacks/appdynamics/v4/appd"
@@ -56,10 +60,12 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		result, err := appd.Build{}.Build(ctx)
 		Expect(err).NotTo(HaveOccurred())
 
-		Expect(result.Layers).To(HaveLen(2))
+		Expect(result.Layers).To(HaveLen(4))
 		Expect(result.Layers[0].Name()).To(Equal("appdynamics-java"))
-		Expect(result.Layers[1].Name()).To(Equal("helper"))
-		Expect(result.Layers[1].(libpak.HelperLayerContributor).Names).To(Equal([]string{"properties"}))
+		Expect(result.Layers[1].Name()).To(Equal("appdynamics-java-configuration"))
+		Expect(result.Layers[2].Name()).To(Equal("appdynamics-provenance"))
+		Expect(result.Layers[3].Name()).To(Equal("helper"))
+		Expect(result.Layers[3].(libpak.HelperLayerContributor).Names).To(Equal([]string{"properties", "preflight", "opentelemetry", "provenance", "unique-host-id"}))
 		Expect(result.BOM.Entries).To(HaveLen(2))
 		Expect(result.BOM.Entries[0].Name).To(Equal("appdynamics-java"))
 		Expect(result.BOM.Entries[1].Name).To(Equal("helper"))
@@ -84,10 +90,12 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		result, err := appd.Build{}.Build(ctx)
 		Expect(err).NotTo(HaveOccurred())
 
-		Expect(result.Layers).To(HaveLen(2))
+		Expect(result.Layers).To(HaveLen(4))
 		Expect(result.Layers[0].Name()).To(Equal("appdynamics-java"))
-		Expect(result.Layers[1].Name()).To(Equal("helper"))
-		Expect(result.Layers[1].(libpak.HelperLayerContributor).Names).To(Equal([]string{"properties"}))
+		Expect(result.Layers[1].Name()).To(Equal("appdynamics-java-configuration"))
+		Expect(result.Layers[2].Name()).To(Equal("appdynamics-provenance"))
+		Expect(result.Layers[3].Name()).To(Equal("helper"))
+		Expect(result.Layers[3].(libpak.HelperLayerContributor).Names).To(Equal([]string{"properties", "preflight", "opentelemetry", "provenance", "unique-host-id"}))
 		Expect(result.BOM.Entries).To(HaveLen(2))
 		Expect(result.BOM.Entries[0].Name).To(Equal("appdynamics-java"))
 		Expect(result.BOM.Entries[1].Name).To(Equal("helper"))
@@ -123,8 +131,8 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			result, err := appd.Build{}.Build(ctx)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(result.Layers).To(HaveLen(2))
-			Expect(result.Layers[0].(appd.JavaAgent).ExternalConfigurationDependency).To(Equal(&libpak.BuildpackDependency{
+			Expect(result.Layers).To(HaveLen(4))
+			Expect(result.Layers[1].(appd.JavaConfiguration).ExternalConfigurationDependency).To(Equal(&libpak.BuildpackDependency{
 				ID:      "appdynamics-external-configuration",
 				Name:    "AppDynamics External Configuration",
 				Version: "test-version",
@@ -134,7 +142,7 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 				CPEs:    []string{"cpe:2.3:a:appdynamics:external-configuration:test-version:*:*:*:*:*:*:*"},
 				PURL:    "pkg:generic/appdynamics-external-configuration@test-version",
 			}))
-			Expect(result.Layers[1].(libpak.HelperLayerContributor).Names).To(Equal([]string{"properties"}))
+			Expect(result.Layers[3].(libpak.HelperLayerContributor).Names).To(Equal([]string{"properties", "preflight", "opentelemetry", "provenance", "unique-host-id"}))
 
 			Expect(result.BOM.Entries).To(HaveLen(3))
 			Expect(result.BOM.Entries[0].Name).To(Equal("appdynamics-java"))
@@ -161,8 +169,8 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			result, err := appd.Build{}.Build(ctx)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(result.Layers).To(HaveLen(2))
-			Expect(result.Layers[0].(appd.JavaAgent).ExternalConfigurationDependency).To(Equal(&libpak.BuildpackDependency{
+			Expect(result.Layers).To(HaveLen(4))
+			Expect(result.Layers[1].(appd.JavaConfiguration).ExternalConfigurationDependency).To(Equal(&libpak.BuildpackDependency{
 				ID:      "appdynamics-external-configuration",
 				Name:    "AppDynamics External Configuration",
 				Version: "test-version",
@@ -172,7 +180,7 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 				CPEs:    []string{"cpe:2.3:a:appdynamics:external-configuration:test-version:*:*:*:*:*:*:*"},
 				PURL:    "pkg:generic/appdynamics-external-configuration@test-version",
 			}))
-			Expect(result.Layers[1].(libpak.HelperLayerContributor).Names).To(Equal([]string{"properties"}))
+			Expect(result.Layers[3].(libpak.HelperLayerContributor).Names).To(Equal([]string{"properties", "preflight", "opentelemetry", "provenance", "unique-host-id"}))
 
 			Expect(result.BOM.Entries).To(HaveLen(3))
 			Expect(result.BOM.Entries[0].Name).To(Equal("appdynamics-java"))
@@ -181,6 +189,242 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	it("contributes default application name", func() {
+		ctx.Application.Path = t.TempDir()
+		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "pom.xml"),
+			[]byte("<project><artifactId>test-artifact</artifactId></project>"), 0644)).To(Succeed())
+		ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "appdynamics-java"})
+		ctx.Buildpack.Metadata = map[string]interface{}{
+			"dependencies": []map[string]interface{}{
+				{
+					"id":      "appdynamics-java",
+					"version": "1.1.1",
+					"stacks":  []interface{}{"test-stack-id"},
+				},
+			},
+		}
+		ctx.Buildpack.API = "0.7"
+		ctx.StackID = "test-stack-id"
+
+		result, err := appd.Build{}.Build(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(result.Layers[3].Name()).To(Equal("appdynamics-launch-defaults"))
+		Expect(result.Layers[3].(appd.LaunchDefaults).Defaults).To(Equal(map[string]string{
+			"APPDYNAMICS_AGENT_APPLICATION_NAME": "test-artifact",
+			"APPDYNAMICS_AGENT_TIER_NAME":        "test-artifact",
+		}))
+	})
+
+	it("contributes binding defaults with $BP_APPD_BINDING_DEFAULTS", func() {
+		t.Setenv("BP_APPD_BINDING_DEFAULTS", "true")
+		t.Setenv("BP_APPD_ENABLED", "true")
+		ctx.Application.Path = t.TempDir()
+		ctx.Platform.Bindings = libcnb.Bindings{
+			{
+				Name: "test-binding",
+				Type: "AppDynamics",
+				Secret: map[string]string{
+					"agent-account-access-key": "test-access-key",
+					"agent-application-name":   "test-application",
+					"controller-host-name":     "test-host",
+				},
+			},
+		}
+		ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "appdynamics-java"})
+		ctx.Buildpack.Metadata = map[string]interface{}{
+			"dependencies": []map[string]interface{}{
+				{
+					"id":      "appdynamics-java",
+					"version": "1.1.1",
+					"stacks":  []interface{}{"test-stack-id"},
+				},
+			},
+		}
+		ctx.Buildpack.API = "0.7"
+		ctx.StackID = "test-stack-id"
+
+		result, err := appd.Build{}.Build(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(result.Layers[3].Name()).To(Equal("appdynamics-launch-defaults"))
+		Expect(result.Layers[3].(appd.LaunchDefaults).Deactivate).To(BeFalse())
+		Expect(result.Layers[3].(appd.LaunchDefaults).Defaults).To(Equal(map[string]string{
+			"APPDYNAMICS_AGENT_APPLICATION_NAME": "test-application",
+			"APPDYNAMICS_CONTROLLER_HOST_NAME":   "test-host",
+		}))
+		ctx.Platform.Bindings = nil
+	})
+
+	it("contributes deactivation marker with $BP_APPD_ENABLED and no binding", func() {
+		t.Setenv("BP_APPD_ENABLED", "true")
+		ctx.Application.Path = t.TempDir()
+		ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "appdynamics-java"})
+		ctx.Buildpack.Metadata = map[string]interface{}{
+			"dependencies": []map[string]interface{}{
+				{
+					"id":      "appdynamics-java",
+					"version": "1.1.1",
+					"stacks":  []interface{}{"test-stack-id"},
+				},
+			},
+		}
+		ctx.Buildpack.API = "0.7"
+		ctx.StackID = "test-stack-id"
+
+		result, err := appd.Build{}.Build(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(result.Layers[3].Name()).To(Equal("appdynamics-launch-defaults"))
+		Expect(result.Layers[3].(appd.LaunchDefaults).Deactivate).To(BeTrue())
+		Expect(result.Layers[3].(appd.LaunchDefaults).Defaults).To(BeEmpty())
+	})
+
+	it("logs build summary", func() {
+		buffer := bytes.NewBuffer(nil)
+		t.Setenv("BP_APPD_EXT_CONF_URI", "test-uri")
+		t.Setenv("BP_APPD_EXT_CONF_VERSION", "test-version")
+		ctx.Layers.Path = "/test/layers"
+		ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "appdynamics-java"})
+		ctx.Buildpack.Metadata = map[string]interface{}{
+			"dependencies": []map[string]interface{}{
+				{
+					"id":      "appdynamics-java",
+					"name":    "AppDynamics Java Agent",
+					"version": "1.1.1",
+					"uri":     "test-agent-uri",
+					"stacks":  []interface{}{"test-stack-id"},
+				},
+			},
+		}
+		ctx.Buildpack.API = "0.7"
+		ctx.StackID = "test-stack-id"
+
+		_, err := appd.Build{Logger: bard.NewLogger(buffer)}.Build(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(buffer.String()).To(ContainSubstring("AppDynamics build summary"))
+		Expect(buffer.String()).To(ContainSubstring("AppDynamics Java Agent 1.1.1 from test-agent-uri"))
+		Expect(buffer.String()).To(ContainSubstring("AppDynamics External Configuration test-version from test-uri"))
+		Expect(buffer.String()).To(ContainSubstring("Layer /test/layers/appdynamics-java-configuration"))
+		Expect(buffer.String()).To(ContainSubstring("Layer /test/layers/helper"))
+	})
+
+	it("masks credentials in logged configuration", func() {
+		buffer := bytes.NewBuffer(nil)
+		t.Setenv("BP_APPD_EXT_CONF_AUTH_TOKEN", "test-token")
+		ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "appdynamics-java"})
+		ctx.Buildpack.Metadata = map[string]interface{}{
+			"configurations": []map[string]interface{}{
+				{"name": "BP_APPD_EXT_CONF_AUTH_TOKEN", "description": "test-description", "build": true},
+			},
+			"dependencies": []map[string]interface{}{
+				{
+					"id":      "appdynamics-java",
+					"version": "1.1.1",
+					"stacks":  []interface{}{"test-stack-id"},
+				},
+			},
+		}
+		ctx.Buildpack.API = "0.7"
+		ctx.StackID = "test-stack-id"
+
+		_, err := appd.Build{Logger: bard.NewLogger(buffer)}.Build(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(buffer.String()).To(ContainSubstring("$BP_APPD_EXT_CONF_AUTH_TOKEN  ********"))
+		Expect(buffer.String()).NotTo(ContainSubstring("test-token"))
+		Expect(os.Getenv("BP_APPD_EXT_CONF_AUTH_TOKEN")).To(Equal("test-token"))
+	})
+
+	context("custom Java agent", func() {
+		it.Before(func() {
+			ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "appdynamics-java"})
+			ctx.Buildpack.Metadata = map[string]interface{}{
+				"dependencies": []map[string]interface{}{
+					{
+						"id":      "appdynamics-java",
+						"version": "1.1.1",
+						"stacks":  []interface{}{"test-stack-id"},
+					},
+				},
+			}
+			ctx.Buildpack.API = "0.7"
+			ctx.StackID = "test-stack-id"
+			t.Setenv("BP_APPD_JAVA_AGENT_VERSION", "test-version")
+		})
+
+		it("contributes agent from $BP_APPD_JAVA_AGENT_URI", func() {
+			t.Setenv("BP_APPD_JAVA_AGENT_URI", "test-uri")
+			t.Setenv("BP_APPD_JAVA_AGENT_SHA256", "test-sha256")
+
+			result, err := appd.Build{}.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].(appd.JavaAgent).AgentDependency).To(Equal(libpak.BuildpackDependency{
+				ID:      "appdynamics-java",
+				Name:    "AppDynamics Java Agent",
+				Version: "test-version",
+				URI:     "test-uri",
+				SHA256:  "test-sha256",
+				Stacks:  []string{ctx.StackID},
+				CPEs:    []string{"cpe:2.3:a:appdynamics:java-agent:test-version:*:*:*:*:*:*:*"},
+				PURL:    "pkg:generic/appdynamics-java-agent@test-version",
+			}))
+			Expect(result.Layers[1].(appd.JavaConfiguration).AgentDependency.URI).To(Equal("test-uri"))
+			Expect(result.BOM.Entries[0].Metadata["uri"]).To(Equal("test-uri"))
+		})
+
+		it("contributes agent from $BP_APPD_JAVA_AGENT_PATH", func() {
+			ctx.Application.Path = t.TempDir()
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "agent"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "agent", "agent.zip"), []byte("test-agent"), 0644)).To(Succeed())
+			t.Setenv("BP_APPD_JAVA_AGENT_PATH", "agent/agent.zip")
+
+			result, err := appd.Build{}.Build(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			dep := result.Layers[0].(appd.JavaAgent).AgentDependency
+			Expect(dep.URI).To(Equal(fmt.Sprintf("file://%s", filepath.Join(ctx.Application.Path, "agent", "agent.zip"))))
+			Expect(dep.SHA256).To(Equal("9bcb1d02c50ee35107a0d0f4a3f9a46d1af6759fca9cef3b10d6131db1474fd5"))
+			Expect(dep.Version).To(Equal("test-version"))
+		})
+
+		it("fails if $BP_APPD_JAVA_AGENT_PATH does not match $BP_APPD_JAVA_AGENT_SHA256", func() {
+			ctx.Application.Path = t.TempDir()
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "agent.zip"), []byte("test-agent"), 0644)).To(Succeed())
+			t.Setenv("BP_APPD_JAVA_AGENT_PATH", "agent.zip")
+			t.Setenv("BP_APPD_JAVA_AGENT_SHA256", "test-sha256")
+
+			_, err := appd.Build{}.Build(ctx)
+			Expect(err).To(MatchError(ContainSubstring("does not match expected test-sha256")))
+		})
+
+		it("fails if $BP_APPD_JAVA_AGENT_URI is set without $BP_APPD_JAVA_AGENT_SHA256", func() {
+			t.Setenv("BP_APPD_JAVA_AGENT_URI", "test-uri")
+
+			_, err := appd.Build{}.Build(ctx)
+			Expect(err).To(MatchError(ContainSubstring("$BP_APPD_JAVA_AGENT_SHA256 must be set with $BP_APPD_JAVA_AGENT_URI")))
+		})
+
+		it("fails without $BP_APPD_JAVA_AGENT_VERSION", func() {
+			t.Setenv("BP_APPD_JAVA_AGENT_URI", "test-uri")
+			t.Setenv("BP_APPD_JAVA_AGENT_SHA256", "test-sha256")
+			t.Setenv("BP_APPD_JAVA_AGENT_VERSION", "")
+
+			_, err := appd.Build{}.Build(ctx)
+			Expect(err).To(MatchError(ContainSubstring("$BP_APPD_JAVA_AGENT_VERSION must be set with a custom Java agent")))
+		})
+
+		it("fails if both $BP_APPD_JAVA_AGENT_URI and $BP_APPD_JAVA_AGENT_PATH are set", func() {
+			t.Setenv("BP_APPD_JAVA_AGENT_URI", "test-uri")
+			t.Setenv("BP_APPD_JAVA_AGENT_PATH", "agent.zip")
+
+			_, err := appd.Build{}.Build(ctx)
+			Expect(err).To(MatchError(ContainSubstring("only one of $BP_APPD_JAVA_AGENT_URI and $BP_APPD_JAVA_AGENT_PATH may be set")))
+		})
+	})
+
 	it("contributes PHP agent API <= 0.6", func() {
 		ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "appdynamics-php"})
 		ctx.Buildpack.Metadata = map[string]interface{}{
@@ -201,7 +445,7 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		Expect(result.Layers).To(HaveLen(2))
 		Expect(result.Layers[0].Name()).To(Equal("appdynamics-php"))
 		Expect(result.Layers[1].Name()).To(Equal("helper"))
-		Expect(result.Layers[1].(libpak.HelperLayerContributor).Names).To(Equal([]string{"properties"}))
+		Expect(result.Layers[1].(libpak.HelperLayerContributor).Names).To(Equal([]string{"properties", "preflight", "opentelemetry", "provenance", "unique-host-id"}))
 
 		Expect(result.BOM.Entries).To(HaveLen(2))
 		Expect(result.BOM.Entries[0].Name).To(Equal("appdynamics-php"))
@@ -230,11 +474,36 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		Expect(result.Layers).To(HaveLen(2))
 		Expect(result.Layers[0].Name()).To(Equal("appdynamics-php"))
 		Expect(result.Layers[1].Name()).To(Equal("helper"))
-		Expect(result.Layers[1].(libpak.HelperLayerContributor).Names).To(Equal([]string{"properties"}))
+		Expect(result.Layers[1].(libpak.HelperLayerContributor).Names).To(Equal([]string{"properties", "preflight", "opentelemetry", "provenance", "unique-host-id"}))
 
 		Expect(result.BOM.Entries).To(HaveLen(2))
 		Expect(result.BOM.Entries[0].Name).To(Equal("appdynamics-php"))
 		Expect(result.BOM.Entries[1].Name).To(Equal("helper"))
 	})
 
+	it("contributes configured agents", func() {
+		ctx.Plan.Entries = append(ctx.Plan.Entries, libcnb.BuildpackPlanEntry{Name: "test-plan"})
+		ctx.Buildpack.Metadata = map[string]interface{}{
+			"dependencies": []map[string]interface{}{
+				{
+					"id":      "test-dependency",
+					"version": "1.1.1",
+					"stacks":  []interface{}{"test-stack-id"},
+				},
+			},
+		}
+		ctx.Buildpack.API = "0.7"
+		ctx.StackID = "test-stack-id"
+
+		result, err := appd.Build{Agents: []appd.Agent{testAgent{}}}.Build(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(result.Layers).To(HaveLen(2))
+		Expect(result.Layers[0].Name()).To(Equal("test-layer"))
+		Expect(result.Layers[1].Name()).To(Equal("helper"))
+		Expect(result.BOM.Entries).To(HaveLen(2))
+		Expect(result.BOM.Entries[0].Name).To(Equal("test-dependency"))
+		Expect(result.BOM.Entries[0].Metadata["version"]).To(Equal("1.1.1"))
+		Expect(result.BOM.Entries[1].Name).To(Equal("helper"))
+	})
 }
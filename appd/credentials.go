@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bindings"
+)
+
+// Credentials authenticate the download of the external configuration. They are intentionally kept out of layer
+// metadata, the BOM and logs.
+type Credentials struct {
+	Token    string
+	Username string
+	Password string
+}
+
+// ResolveExternalConfigurationCredentials returns the credentials from a binding of type appdynamics-config-auth, or
+// from $BP_APPD_EXT_CONF_AUTH_TOKEN or $BP_APPD_EXT_CONF_AUTH_USERNAME and $BP_APPD_EXT_CONF_AUTH_PASSWORD if no such
+// binding exists. Returns nil if no credentials are configured.
+func ResolveExternalConfigurationCredentials(binds libcnb.Bindings, configurationResolver libpak.ConfigurationResolver) (*Credentials, error) {
+	var c Credentials
+
+	if b, ok, err := bindings.ResolveOne(binds, bindings.OfType("appdynamics-config-auth")); err != nil {
+		return nil, fmt.Errorf("unable to resolve binding appdynamics-config-auth\n%w", err)
+	} else if ok {
+		c = Credentials{Token: b.Secret["token"], Username: b.Secret["username"], Password: b.Secret["password"]}
+	} else {
+		c.Token, _ = configurationResolver.Resolve("BP_APPD_EXT_CONF_AUTH_TOKEN")
+		c.Username, _ = configurationResolver.Resolve("BP_APPD_EXT_CONF_AUTH_USERNAME")
+		c.Password, _ = configurationResolver.Resolve("BP_APPD_EXT_CONF_AUTH_PASSWORD")
+	}
+
+	switch {
+	case c.Token != "" && c.Username != "":
+		return nil, fmt.Errorf("only one of a token or a username and password may be configured")
+	case c.Token == "" && c.Username == "" && c.Password != "":
+		return nil, fmt.Errorf("a password requires a username")
+	case c.Token == "" && c.Username == "":
+		return nil, nil
+	}
+
+	return &c, nil
+}
+
+// SensitiveConfigurations are the configurations holding credentials. They are declared in buildpack.toml so that they
+// are discoverable, but their values are masked when the configuration is logged.
+var SensitiveConfigurations = []string{
+	"BP_APPD_EXT_CONF_AUTH_PASSWORD",
+	"BP_APPD_EXT_CONF_AUTH_TOKEN",
+	"BP_APPD_EXT_CONF_AUTH_USERNAME",
+}
+
+// RequestModifier returns a libpak.RequestModifierFunc that adds the credentials to the Authorization header.
+func (c Credentials) RequestModifier() libpak.RequestModifierFunc {
+	return func(request *http.Request) (*http.Request, error) {
+		if c.Token != "" {
+			request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+		} else {
+			request.SetBasicAuth(c.Username, c.Password)
+		}
+
+		return request, nil
+	}
+}
+
+// String redacts the credentials so that they cannot be logged accidentally.
+func (c Credentials) String() string {
+	if c.Token != "" {
+		return "Credentials{Token: [REDACTED]}"
+	}
+
+	return "Credentials{Username: [REDACTED], Password: [REDACTED]}"
+}
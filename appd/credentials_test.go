@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/appd"
+)
+
+func testCredentials(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	it("returns nil without credentials", func() {
+		Expect(appd.ResolveExternalConfigurationCredentials(nil, libpak.ConfigurationResolver{})).To(BeNil())
+	})
+
+	it("resolves token from binding", func() {
+		t.Setenv("BP_APPD_EXT_CONF_AUTH_USERNAME", "test-env-username")
+
+		Expect(appd.ResolveExternalConfigurationCredentials(libcnb.Bindings{
+			{Name: "test-binding", Type: "appdynamics-config-auth", Secret: map[string]string{"token": "test-token"}},
+		}, libpak.ConfigurationResolver{})).To(Equal(&appd.Credentials{Token: "test-token"}))
+	})
+
+	it("resolves username and password from environment", func() {
+		t.Setenv("BP_APPD_EXT_CONF_AUTH_USERNAME", "test-username")
+		t.Setenv("BP_APPD_EXT_CONF_AUTH_PASSWORD", "test-password")
+
+		Expect(appd.ResolveExternalConfigurationCredentials(nil, libpak.ConfigurationResolver{})).
+			To(Equal(&appd.Credentials{Username: "test-username", Password: "test-password"}))
+	})
+
+	it("fails with token and username", func() {
+		_, err := appd.ResolveExternalConfigurationCredentials(libcnb.Bindings{
+			{Name: "test-binding", Type: "appdynamics-config-auth", Secret: map[string]string{"token": "test-token", "username": "test-username"}},
+		}, libpak.ConfigurationResolver{})
+		Expect(err).To(MatchError("only one of a token or a username and password may be configured"))
+	})
+
+	it("sets bearer token", func() {
+		req, err := http.NewRequest("GET", "https://localhost", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		req, err = appd.Credentials{Token: "test-token"}.RequestModifier()(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Header.Get("Authorization")).To(Equal("Bearer test-token"))
+	})
+
+	it("sets basic auth", func() {
+		req, err := http.NewRequest("GET", "https://localhost", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		req, err = appd.Credentials{Username: "test-username", Password: "test-password"}.RequestModifier()(req)
+		Expect(err).NotTo(HaveOccurred())
+		username, password, ok := req.BasicAuth()
+		Expect(ok).To(BeTrue())
+		Expect(username).To(Equal("test-username"))
+		Expect(password).To(Equal("test-password"))
+	})
+
+	it("redacts credentials", func() {
+		c := &appd.Credentials{Username: "test-username", Password: "test-password"}
+		Expect(fmt.Sprintf("%+v", c)).NotTo(ContainSubstring("test-"))
+		Expect(fmt.Sprintf("%v", *c)).NotTo(ContainSubstring("test-"))
+	})
+}
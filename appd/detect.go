@@ -21,42 +21,46 @@ import (
 
 	"github.com/buildpacks/libcnb"
 	"github.com/paketo-buildpacks/libpak/bard"
-	"github.com/paketo-buildpacks/libpak/bindings"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/helper"
 )
 
 type Detect struct {
+	Agents []Agent
 	Logger bard.Logger
 }
 
 func (d Detect) Detect(context libcnb.DetectContext) (libcnb.DetectResult, error) {
-	if _, ok, err := bindings.ResolveOne(context.Platform.Bindings, bindings.OfType("AppDynamics")); err != nil {
+	if _, ok, err := helper.ResolveBinding(context.Platform.Bindings, "BP_APPD_BINDING_NAME"); err != nil {
 		return libcnb.DetectResult{}, fmt.Errorf("unable to resolve binding AppDynamics\n%w", err)
+	} else if !ok && sherpa.ResolveBool("BP_APPD_ENABLED") {
+		d.Logger.Info("No binding of type 'AppDynamics' found, contributing agent because $BP_APPD_ENABLED is set")
 	} else if !ok {
 		d.Logger.Info("SKIPPED: No binding of type 'AppDynamics' found")
 		return libcnb.DetectResult{Pass: false}, nil
 	}
 
-	return libcnb.DetectResult{
-		Pass: true,
-		Plans: []libcnb.BuildPlan{
-			{
-				Provides: []libcnb.BuildPlanProvide{
-					{Name: "appdynamics-java"},
-				},
-				Requires: []libcnb.BuildPlanRequire{
-					{Name: "appdynamics-java"},
-					{Name: "jvm-application"},
-				},
+	var plans []libcnb.BuildPlan
+	for _, a := range d.agents() {
+		plans = append(plans, libcnb.BuildPlan{
+			Provides: []libcnb.BuildPlanProvide{
+				{Name: a.PlanName()},
 			},
-			{
-				Provides: []libcnb.BuildPlanProvide{
-					{Name: "appdynamics-php"},
-				},
-				Requires: []libcnb.BuildPlanRequire{
-					{Name: "appdynamics-php"},
-					{Name: "php"},
-				},
+			Requires: []libcnb.BuildPlanRequire{
+				{Name: a.PlanName()},
+				{Name: a.RequiredPlan()},
 			},
-		},
-	}, nil
+		})
+	}
+
+	return libcnb.DetectResult{Pass: true, Plans: plans}, nil
+}
+
+func (d Detect) agents() []Agent {
+	if d.Agents == nil {
+		return Agents
+	}
+
+	return d.Agents
 }
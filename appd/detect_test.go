@@ -38,6 +38,15 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		Expect(detect.Detect(ctx)).To(Equal(libcnb.DetectResult{}))
 	})
 
+	it("passes without service if $BP_APPD_ENABLED is set", func() {
+		t.Setenv("BP_APPD_ENABLED", "true")
+
+		result, err := detect.Detect(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Pass).To(BeTrue())
+		Expect(result.Plans).To(HaveLen(2))
+	})
+
 	it("passes with service", func() {
 		ctx.Platform.Bindings = libcnb.Bindings{
 			{Name: "test-service", Type: "AppDynamics"},
@@ -67,4 +76,78 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 			},
 		}))
 	})
+
+	it("fails with multiple services", func() {
+		ctx.Platform.Bindings = libcnb.Bindings{
+			{Name: "test-service-1", Type: "AppDynamics"},
+			{Name: "test-service-2", Type: "AppDynamics"},
+		}
+
+		_, err := detect.Detect(ctx)
+		Expect(err).To(MatchError(ContainSubstring("set $BP_APPD_BINDING_NAME to one of test-service-1, test-service-2")))
+	})
+
+	it("passes with service selected by $BP_APPD_BINDING_NAME", func() {
+		t.Setenv("BP_APPD_BINDING_NAME", "test-service-2")
+		ctx.Platform.Bindings = libcnb.Bindings{
+			{Name: "test-service-1", Type: "AppDynamics"},
+			{Name: "test-service-2", Type: "AppDynamics"},
+		}
+
+		result, err := detect.Detect(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Pass).To(BeTrue())
+	})
+
+	it("passes with configured agents", func() {
+		ctx.Platform.Bindings = libcnb.Bindings{
+			{Name: "test-service", Type: "AppDynamics"},
+		}
+		detect.Agents = []appd.Agent{testAgent{}}
+
+		Expect(detect.Detect(ctx)).To(Equal(libcnb.DetectResult{
+			Pass: true,
+			Plans: []libcnb.BuildPlan{
+				{
+					Provides: []libcnb.BuildPlanProvide{
+						{Name: "test-plan"},
+					},
+					Requires: []libcnb.BuildPlanRequire{
+						{Name: "test-plan"},
+						{Name: "test-required-plan"},
+					},
+				},
+			},
+		}))
+	})
+}
+
+type testAgent struct{}
+
+func (testAgent) PlanName() string {
+	return "test-plan"
+}
+
+func (testAgent) RequiredPlan() string {
+	return "test-required-plan"
+}
+
+func (testAgent) DependencyID() string {
+	return "test-dependency"
+}
+
+func (testAgent) Layers(context appd.AgentContext) ([]libcnb.LayerContributor, []libcnb.BOMEntry, error) {
+	return []libcnb.LayerContributor{testLayer{}}, []libcnb.BOMEntry{
+		{Name: context.Dependency.ID, Metadata: map[string]interface{}{"version": context.Dependency.Version}},
+	}, nil
+}
+
+type testLayer struct{}
+
+func (testLayer) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	return layer, nil
+}
+
+func (testLayer) Name() string {
+	return "test-layer"
 }
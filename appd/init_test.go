@@ -25,9 +25,17 @@ import (
 
 func TestUnit(t *testing.T) {
 	suite := spec.New("appd", spec.Report(report.Terminal{}))
+	suite("ApplicationName", testApplicationName)
 	suite("Build", testBuild)
+	suite("Credentials", testCredentials)
 	suite("Detect", testDetect)
 	suite("JavaAgent", testJavaAgent)
+	suite("JavaConfiguration", testJavaConfiguration)
+	suite("LaunchDefaults", testLaunchDefaults)
 	suite("PHPAgent", testPHPAgent)
+	suite("Provenance", testProvenance)
+	suite("SBOM", testSBOM)
+	suite("VersionDirectory", testVersionDirectory)
+	suite("Verification", testVerification)
 	suite.Run(t)
 }
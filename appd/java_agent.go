@@ -17,12 +17,14 @@
 package appd
 
 import (
+	"archive/zip"
+	"bufio"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"strconv"
-
-	"github.com/paketo-buildpacks/libpak/sbom"
+	"regexp"
+	"strings"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/heroku/color"
@@ -32,88 +34,86 @@ import (
 	"github.com/paketo-buildpacks/libpak/sherpa"
 )
 
+// JavaAgentSlimAllowList contains the patterns, relative to the agent layer, of the files and directories required by
+// the agent at runtime. When $BP_APPD_JAVA_SLIM is set, everything else is removed from the agent layer.
+var JavaAgentSlimAllowList = []string{
+	"LICENSE*",
+	"javaagent.jar",
+	"ver*/LICENSE*",
+	"ver*/conf",
+	"ver*/external-services",
+	"ver*/javaagent.jar",
+	"ver*/lib",
+	"ver*/logs",
+	"ver*/multi-release",
+	"ver*/sdk-plugins",
+}
+
 type JavaAgent struct {
-	AgentDependency                 libpak.BuildpackDependency
-	BuildpackPath                   string
-	ConfigurationResolver           libpak.ConfigurationResolver
-	DependencyCache                 libpak.DependencyCache
-	ExternalConfigurationDependency *libpak.BuildpackDependency
-	LayerContributor                libpak.LayerContributor
-	Logger                          bard.Logger
+	AgentDependency       libpak.BuildpackDependency
+	ConfigurationResolver libpak.ConfigurationResolver
+	DependencyCache       libpak.DependencyCache
+	LayerContributor      libpak.LayerContributor
+	Logger                bard.Logger
 }
 
-func NewJavaAgent(buildpackPath string, agentDependency libpak.BuildpackDependency, configurationResolver libpak.ConfigurationResolver, externalConfigurationDependency *libpak.BuildpackDependency, cache libpak.DependencyCache) (JavaAgent, []libcnb.BOMEntry) {
+func NewJavaAgent(agentDependency libpak.BuildpackDependency, configurationResolver libpak.ConfigurationResolver, cache libpak.DependencyCache) (JavaAgent, libcnb.BOMEntry) {
+	metadata := map[string]interface{}{
+		"dependencies": []libpak.BuildpackDependency{agentDependency},
+	}
 
-	dependencies := []libpak.BuildpackDependency{agentDependency}
+	if s, ok := configurationResolver.Resolve("BP_APPD_JAVA_VERSION_DIR"); ok {
+		metadata["version-directory"] = s
+	}
 
-	if externalConfigurationDependency != nil {
-		dependencies = append(dependencies, *externalConfigurationDependency)
+	if configurationResolver.ResolveBool("BP_APPD_JAVA_SLIM") {
+		metadata["slim"] = JavaAgentSlimAllowList
 	}
 
 	j := JavaAgent{
-		AgentDependency:                 agentDependency,
-		BuildpackPath:                   buildpackPath,
-		ConfigurationResolver:           configurationResolver,
-		DependencyCache:                 cache,
-		ExternalConfigurationDependency: externalConfigurationDependency,
+		AgentDependency:       agentDependency,
+		ConfigurationResolver: configurationResolver,
+		DependencyCache:       cache,
 		LayerContributor: libpak.NewLayerContributor(
 			fmt.Sprintf("%s %s", agentDependency.Name, agentDependency.Version),
-			map[string]interface{}{
-				"dependencies": dependencies,
-			},
-			libcnb.LayerTypes{Launch: true},
+			metadata,
+			libcnb.LayerTypes{Cache: true, Launch: true},
 		),
 	}
 
-	var bomEntries []libcnb.BOMEntry
 	entry := agentDependency.AsBOMEntry()
 	entry.Metadata["layer"] = j.Name()
 	entry.Launch = true
-	bomEntries = append(bomEntries, entry)
 
-	if externalConfigurationDependency != nil {
-		entry := externalConfigurationDependency.AsBOMEntry()
-		entry.Metadata["layer"] = j.Name()
-		entry.Launch = true
-		bomEntries = append(bomEntries, entry)
-	}
-
-	return j, bomEntries
+	return j, entry
 }
 
 func (j JavaAgent) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
 	j.LayerContributor.Logger = j.Logger
-	var syftArtifacts []sbom.SyftArtifact
 
 	return j.LayerContributor.Contribute(layer, func() (libcnb.Layer, error) {
 		if err := j.ContributeAgent(layer); err != nil {
 			return libcnb.Layer{}, fmt.Errorf("unable to contribute agent\n%w", err)
 		}
-		if syftArtifact, err := j.AgentDependency.AsSyftArtifact(); err != nil {
-			return libcnb.Layer{}, fmt.Errorf("unable to get Syft Artifact for dependency: %s, \n%w", j.AgentDependency.Name, err)
-		} else {
-			syftArtifacts = append(syftArtifacts, syftArtifact)
-		}
-
-		if err := j.ContributeConfiguration(layer); err != nil {
-			return libcnb.Layer{}, fmt.Errorf("unable to contribute configuration\n%w", err)
-		}
 
-		if j.ExternalConfigurationDependency != nil {
-			if err := j.ContributeExternalConfiguration(layer); err != nil {
-				return libcnb.Layer{}, fmt.Errorf("unable to contribute external configuration\n%w", err)
+		var properties []CycloneDXProperty
+		if j.ConfigurationResolver.ResolveBool("BP_APPD_JAVA_SLIM") {
+			pruned, err := j.PruneAgent(layer)
+			if err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to prune agent\n%w", err)
 			}
-			if syftArtifact, err := j.ExternalConfigurationDependency.AsSyftArtifact(); err != nil {
-				return libcnb.Layer{}, fmt.Errorf("unable to get Syft Artifact for dependency: %s, \n%w", j.ExternalConfigurationDependency.Name, err)
-			} else {
-				syftArtifacts = append(syftArtifacts, syftArtifact)
+
+			properties = append(properties, CycloneDXProperty{Name: "appdynamics:slim", Value: "true"})
+			for _, p := range pruned {
+				properties = append(properties, CycloneDXProperty{Name: "appdynamics:slim:pruned", Value: p})
 			}
 		}
 
 		layer.LaunchEnvironment.Appendf("JAVA_TOOL_OPTIONS", " ",
 			"-javaagent:%s", filepath.Join(layer.Path, "javaagent.jar"))
+		layer.LaunchEnvironment.Default("BPI_APPD_AGENT_PATH", layer.Path)
 
-		if err := j.writeDependencySBOM(layer, syftArtifacts); err != nil {
+		if err := writeDependencySBOM(j.Logger, layer, []libpak.BuildpackDependency{j.AgentDependency}, properties...); err != nil {
 			return libcnb.Layer{}, err
 		}
 
@@ -134,115 +134,184 @@ func (j JavaAgent) ContributeAgent(layer libcnb.Layer) error {
 		return fmt.Errorf("unable to extract to %s\n%w", layer.Path, err)
 	}
 
-	return nil
-}
+	if err := j.VerifyAgent(layer); err != nil {
+		return fmt.Errorf("unable to verify agent\n%w", err)
+	}
 
-func (j JavaAgent) ContributeConfiguration(layer libcnb.Layer) error {
-	v, err := VersionDirectory(layer)
+	v, err := VersionDirectory(layer, j.ConfigurationResolver)
 	if err != nil {
 		return fmt.Errorf("unable to determine version directory\n%w", err)
 	}
 
-	file := filepath.Join(v, "conf")
-	if err := os.MkdirAll(file, 0755); err != nil {
-		return fmt.Errorf("unable to create directory %s\n%w", file, err)
+	logDir := filepath.Join(v, "logs")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return fmt.Errorf("unable to create log directory %s\n%w", logDir, err)
 	}
-
-	j.Logger.Bodyf("Copying app-agent-config.xml to %s/conf", v)
-	file = filepath.Join(j.BuildpackPath, "resources", "app-agent-config.xml")
-	in, err := os.Open(file)
-	if err != nil {
-		return fmt.Errorf("unable to open %s\n%w", file, err)
+	// Make version*/logs directory group-writable so that a custom user can run container image (must still be cnb group)
+	if err := os.Chmod(logDir, 0775); err != nil {
+		return fmt.Errorf("unable to set log directory permissions %s\n%w", logDir, err)
 	}
-	defer in.Close()
 
-	file = filepath.Join(v, "conf", "app-agent-config.xml")
-	if err := sherpa.CopyFile(in, file); err != nil {
-		return fmt.Errorf("unable to copy %s to %s\n%w", in.Name(), file, err)
-	}
+	return nil
+}
 
-	j.Logger.Bodyf("Copying custom-activity-correlation.xml to %s/conf", v)
-	file = filepath.Join(j.BuildpackPath, "resources", "custom-activity-correlation.xml")
-	in, err = os.Open(file)
-	if err != nil {
-		return fmt.Errorf("unable to open %s\n%w", file, err)
+// PruneAgent removes all files from the agent that are not matched by JavaAgentSlimAllowList and returns the paths of
+// the removed files and directories relative to the layer.
+func (j JavaAgent) PruneAgent(layer libcnb.Layer) ([]string, error) {
+	var pruned []string
+
+	if err := filepath.WalkDir(layer.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(layer.Path, path)
+		if err != nil {
+			return fmt.Errorf("unable to determine relative path of %s\n%w", path, err)
+		}
+
+		if rel == "." {
+			return nil
+		}
+
+		keep, descend := slimAllowed(rel)
+		if descend {
+			return nil
+		}
+
+		if !keep {
+			if err := os.RemoveAll(path); err != nil {
+				return fmt.Errorf("unable to remove %s\n%w", path, err)
+			}
+			pruned = append(pruned, filepath.ToSlash(rel))
+		}
+
+		if d.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("unable to walk %s\n%w", layer.Path, err)
 	}
-	defer in.Close()
 
-	file = filepath.Join(v, "conf", "custom-activity-correlation.xml")
-	if err := sherpa.CopyFile(in, file); err != nil {
-		return fmt.Errorf("unable to copy %s to %s\n%w", in.Name(), file, err)
+	j.Logger.Bodyf("Pruned %d unused files and directories from agent", len(pruned))
+	for _, p := range pruned {
+		j.Logger.Debugf("Pruned %s", p)
 	}
 
-	file = filepath.Join(v, "conf", "logging")
-	if err := os.MkdirAll(file, 0755); err != nil {
-		return fmt.Errorf("unable to create directory %s\n%w", file, err)
+	return pruned, nil
+}
+
+// slimAllowed returns whether a path is matched by an entry in JavaAgentSlimAllowList, and whether it is a directory
+// that must be descended into because it is a parent of an entry.
+func slimAllowed(path string) (bool, bool) {
+	p := strings.Split(filepath.ToSlash(path), "/")
+
+	descend := false
+	for _, a := range JavaAgentSlimAllowList {
+		e := strings.Split(a, "/")
+
+		n := len(e)
+		if len(p) < n {
+			n = len(p)
+		}
+
+		matches := true
+		for i := 0; i < n; i++ {
+			if ok, _ := filepath.Match(e[i], p[i]); !ok {
+				matches = false
+				break
+			}
+		}
+
+		if !matches {
+			continue
+		}
+
+		if len(p) >= len(e) {
+			return true, false
+		}
+		descend = true
 	}
 
-	j.Logger.Bodyf("Copying log4j2.xml to %s/conf/logging", v)
-	file = filepath.Join(j.BuildpackPath, "resources", "log4j2.xml")
-	in, err = os.Open(file)
-	if err != nil {
-		return fmt.Errorf("unable to open %s\n%w", file, err)
+	return false, descend
+}
+
+// VerifyAgent checks that the expanded archive contains javaagent.jar and a version directory and warns if the version in
+// the agent's manifest does not match the version of the dependency.
+func (j JavaAgent) VerifyAgent(layer libcnb.Layer) error {
+	jar := filepath.Join(layer.Path, "javaagent.jar")
+	if ok, err := sherpa.FileExists(jar); err != nil {
+		return fmt.Errorf("unable to check %s\n%w", jar, err)
+	} else if !ok {
+		return fmt.Errorf("javaagent.jar not found in %s, %s does not appear to be an AppDynamics Java agent archive",
+			layer.Path, j.AgentDependency.URI)
 	}
-	defer in.Close()
 
-	file = filepath.Join(v, "conf", "logging", "log4j2.xml")
-	if err := sherpa.CopyFile(in, file); err != nil {
-		return fmt.Errorf("unable to copy %s to %s\n%w", in.Name(), file, err)
+	if _, err := VersionDirectory(layer, j.ConfigurationResolver); err != nil {
+		return fmt.Errorf("%s does not appear to be an AppDynamics Java agent archive\n%w", j.AgentDependency.URI, err)
 	}
 
-	logDir := filepath.Join(v, "logs")
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return fmt.Errorf("unable to create log directory %s\n%w", logDir, err)
+	version, err := JavaAgentVersion(jar)
+	if err != nil {
+		j.Logger.Bodyf("%s unable to determine agent version from %s: %s",
+			color.YellowString("Warning:"), jar, strings.ReplaceAll(err.Error(), "\n", ": "))
+		return nil
 	}
-	// Make version*/logs directory group-writable so that a custom user can run container image (must still be cnb group)
-	if err := os.Chmod(logDir, 0775); err != nil {
-		return fmt.Errorf("unable to set log directory permissions %s\n%w", logDir, err)
+
+	if !matchesVersion(version, j.AgentDependency.Version) {
+		j.Logger.Bodyf("%s agent version %s does not match expected version %s",
+			color.YellowString("Warning:"), version, j.AgentDependency.Version)
+		return nil
 	}
 
+	j.Logger.Bodyf("Verified agent version %s", version)
 	return nil
 }
 
-func (j JavaAgent) ContributeExternalConfiguration(layer libcnb.Layer) error {
-	j.Logger.Header(color.BlueString("%s %s", j.ExternalConfigurationDependency.Name, j.ExternalConfigurationDependency.Version))
+var manifestVersionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
 
-	artifact, err := j.DependencyCache.Artifact(*j.ExternalConfigurationDependency)
+// JavaAgentVersion returns the agent version from the Implementation-Version attribute of the jar's manifest.
+func JavaAgentVersion(jar string) (string, error) {
+	r, err := zip.OpenReader(jar)
 	if err != nil {
-		return fmt.Errorf("unable to get dependency %s\n%w", j.ExternalConfigurationDependency.ID, err)
+		return "", fmt.Errorf("unable to open %s\n%w", jar, err)
 	}
-	defer artifact.Close()
+	defer r.Close()
 
-	j.Logger.Bodyf("Expanding to %s", layer.Path)
+	f, err := r.Open("META-INF/MANIFEST.MF")
+	if err != nil {
+		return "", fmt.Errorf("unable to open manifest\n%w", err)
+	}
+	defer f.Close()
 
-	c := 0
-	if s, ok := j.ConfigurationResolver.Resolve("BP_APPD_EXT_CONF_STRIP"); ok {
-		if c, err = strconv.Atoi(s); err != nil {
-			return fmt.Errorf("unable to parse %s to integer\n%w", s, err)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		k, v, ok := strings.Cut(s.Text(), ":")
+		if !ok || strings.TrimSpace(k) != "Implementation-Version" {
+			continue
 		}
-	}
 
-	v, err := VersionDirectory(layer)
-	if err != nil {
-		return fmt.Errorf("unable to determine version directory\n%w", err)
-	}
+		if version := manifestVersionPattern.FindString(v); version != "" {
+			return version, nil
+		}
 
-	if err := crush.ExtractTarGz(artifact, v, c); err != nil {
-		return fmt.Errorf("unable to expand external configuration\n%w", err)
+		return "", fmt.Errorf("unable to parse version from Implementation-Version %q", strings.TrimSpace(v))
+	}
+	if err := s.Err(); err != nil {
+		return "", fmt.Errorf("unable to read manifest\n%w", err)
 	}
 
-	return nil
+	return "", fmt.Errorf("no Implementation-Version in manifest")
 }
 
-func (j JavaAgent) writeDependencySBOM(layer libcnb.Layer, syftArtifacts []sbom.SyftArtifact) error {
-
-	sbomPath := layer.SBOMPath(libcnb.SyftJSON)
-	dep := sbom.NewSyftDependency(layer.Path, syftArtifacts)
-	j.Logger.Debugf("Writing Syft SBOM at %s: %+v", sbomPath, dep)
-	if err := dep.WriteTo(sbomPath); err != nil {
-		return fmt.Errorf("unable to write SBOM\n%w", err)
+// matchesVersion returns whether actual matches expected, ignoring additional components such as build numbers.
+func matchesVersion(actual string, expected string) bool {
+	if expected == "" {
+		return true
 	}
-	return nil
+
+	return actual == expected || strings.HasPrefix(actual, expected+".")
 }
 
 func (JavaAgent) Name() string {
@@ -17,6 +17,9 @@
 package appd_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -26,6 +29,7 @@ import (
 	"github.com/buildpacks/libcnb"
 	. "github.com/onsi/gomega"
 	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/sclevine/spec"
 
 	"github.com/paketo-buildpacks/appdynamics/v4/appd"
@@ -41,27 +45,15 @@ func testJavaAgent(t *testing.T, context spec.G, it spec.S) {
 	it.Before(func() {
 		var err error
 
-		ctx.Buildpack.Path, err = ioutil.TempDir("", "java-agent-buildpack")
-		Expect(err).NotTo(HaveOccurred())
-
 		ctx.Layers.Path, err = ioutil.TempDir("", "java-agent-layers")
 		Expect(err).NotTo(HaveOccurred())
 	})
 
 	it.After(func() {
-		Expect(os.RemoveAll(ctx.Buildpack.Path)).To(Succeed())
 		Expect(os.RemoveAll(ctx.Layers.Path)).To(Succeed())
 	})
 
 	it("contributes Java agent", func() {
-		Expect(os.MkdirAll(filepath.Join(ctx.Buildpack.Path, "resources"), 0755)).To(Succeed())
-		Expect(ioutil.WriteFile(filepath.Join(ctx.Buildpack.Path, "resources", "app-agent-config.xml"), []byte{}, 0644)).
-			To(Succeed())
-		Expect(ioutil.WriteFile(filepath.Join(ctx.Buildpack.Path, "resources", "custom-activity-correlation.xml"), []byte{}, 0644)).
-			To(Succeed())
-		Expect(ioutil.WriteFile(filepath.Join(ctx.Buildpack.Path, "resources", "log4j2.xml"), []byte{}, 0644)).
-			To(Succeed())
-
 		dep := libpak.BuildpackDependency{
 			ID:     "appdynamics-java",
 			URI:    "https://localhost/stub-appdynamics-agent.zip",
@@ -69,12 +61,11 @@ func testJavaAgent(t *testing.T, context spec.G, it spec.S) {
 		}
 		dc := libpak.DependencyCache{CachePath: "testdata"}
 
-		j, bomEntries := appd.NewJavaAgent(ctx.Buildpack.Path, dep, libpak.ConfigurationResolver{}, nil, dc)
-		Expect(bomEntries).To(HaveLen(1))
-		Expect(bomEntries[0].Name).To(Equal("appdynamics-java"))
-		Expect(bomEntries[0].Metadata["layer"]).To(Equal("appdynamics-java"))
-		Expect(bomEntries[0].Launch).To(BeTrue())
-		Expect(bomEntries[0].Build).To(BeFalse())
+		j, bomEntry := appd.NewJavaAgent(dep, libpak.ConfigurationResolver{}, dc)
+		Expect(bomEntry.Name).To(Equal("appdynamics-java"))
+		Expect(bomEntry.Metadata["layer"]).To(Equal("appdynamics-java"))
+		Expect(bomEntry.Launch).To(BeTrue())
+		Expect(bomEntry.Build).To(BeFalse())
 
 		layer, err := ctx.Layers.Layer("test-layer")
 		Expect(err).NotTo(HaveOccurred())
@@ -83,96 +74,71 @@ func testJavaAgent(t *testing.T, context spec.G, it spec.S) {
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(layer.Launch).To(BeTrue())
+		Expect(layer.Cache).To(BeTrue())
 		Expect(filepath.Join(layer.Path, "javaagent.jar")).To(BeARegularFile())
-		Expect(filepath.Join(layer.Path, "ver4.5.7.25056", "conf", "app-agent-config.xml")).To(BeARegularFile())
-		Expect(filepath.Join(layer.Path, "ver4.5.7.25056", "conf", "custom-activity-correlation.xml")).To(BeARegularFile())
-		Expect(filepath.Join(layer.Path, "ver4.5.7.25056", "conf", "logging", "log4j2.xml")).To(BeARegularFile())
+		Expect(filepath.Join(layer.Path, "ver4.5.7.25056", "logs")).To(BeADirectory())
 		Expect(layer.LaunchEnvironment["JAVA_TOOL_OPTIONS.delim"]).To(Equal(" "))
 		Expect(layer.LaunchEnvironment["JAVA_TOOL_OPTIONS.append"]).To(Equal(fmt.Sprintf("-javaagent:%s",
 			filepath.Join(layer.Path, "javaagent.jar"))))
-	})
+		Expect(layer.LaunchEnvironment["BPI_APPD_AGENT_PATH.default"]).To(Equal(layer.Path))
 
-	it("contributes external configuration", func() {
-		Expect(os.MkdirAll(filepath.Join(ctx.Buildpack.Path, "resources"), 0755)).To(Succeed())
-		Expect(ioutil.WriteFile(filepath.Join(ctx.Buildpack.Path, "resources", "app-agent-config.xml"), []byte{}, 0644)).
-			To(Succeed())
-		Expect(ioutil.WriteFile(filepath.Join(ctx.Buildpack.Path, "resources", "custom-activity-correlation.xml"), []byte{}, 0644)).
-			To(Succeed())
-		Expect(ioutil.WriteFile(filepath.Join(ctx.Buildpack.Path, "resources", "log4j2.xml"), []byte{}, 0644)).
-			To(Succeed())
+		Expect(layer.SBOMPath(libcnb.SyftJSON)).To(BeARegularFile())
+		var cycloneDX appd.CycloneDXDocument
+		b, err := ioutil.ReadFile(layer.SBOMPath(libcnb.CycloneDXJSON))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(json.Unmarshal(b, &cycloneDX)).To(Succeed())
+		Expect(cycloneDX.Components).To(HaveLen(1))
+		Expect(cycloneDX.Components[0].Hashes).To(Equal([]appd.CycloneDXHash{
+			{Algorithm: "SHA-256", Content: "ee23306ce5f7086219c1876652ed323970ebc249f21d1c79b737ac1120284bbf"},
+		}))
+	})
 
-		agentDep := libpak.BuildpackDependency{
+	it("recontributes restored layer without a directory", func() {
+		dep := libpak.BuildpackDependency{
 			ID:     "appdynamics-java",
 			URI:    "https://localhost/stub-appdynamics-agent.zip",
 			SHA256: "ee23306ce5f7086219c1876652ed323970ebc249f21d1c79b737ac1120284bbf",
 		}
-		externalConfigurationDep := libpak.BuildpackDependency{
-			ID:     "appdynamics-external-configuration",
-			URI:    "https://localhost/stub-external-configuration.tar.gz",
-			SHA256: "22e708cfd301430cbcf8d1c2289503d8288d50df519ff4db7cca0ff9fe83c324",
-		}
 		dc := libpak.DependencyCache{CachePath: "testdata"}
 
-		j, bomEntries := appd.NewJavaAgent(ctx.Buildpack.Path, agentDep, libpak.ConfigurationResolver{}, &externalConfigurationDep, dc)
-		Expect(bomEntries).To(HaveLen(2))
-		Expect(bomEntries[0].Name).To(Equal("appdynamics-java"))
-		Expect(bomEntries[0].Metadata["layer"]).To(Equal("appdynamics-java"))
-		Expect(bomEntries[0].Launch).To(BeTrue())
-		Expect(bomEntries[0].Build).To(BeFalse())
-		Expect(bomEntries[1].Name).To(Equal("appdynamics-external-configuration"))
-		Expect(bomEntries[1].Metadata["layer"]).To(Equal("appdynamics-java"))
-		Expect(bomEntries[1].Launch).To(BeTrue())
-		Expect(bomEntries[1].Build).To(BeFalse())
+		j, _ := appd.NewJavaAgent(dep, libpak.ConfigurationResolver{}, dc)
 
 		layer, err := ctx.Layers.Layer("test-layer")
 		Expect(err).NotTo(HaveOccurred())
 
 		layer, err = j.Contribute(layer)
 		Expect(err).NotTo(HaveOccurred())
+		metadata := layer.Metadata
+
+		Expect(os.RemoveAll(layer.Path)).To(Succeed())
+		Expect(ioutil.WriteFile(layer.Path+".toml", []byte{}, 0644)).To(Succeed())
+
+		layer, err = ctx.Layers.Layer("test-layer")
+		Expect(err).NotTo(HaveOccurred())
+		layer.Metadata = metadata
 
-		Expect(filepath.Join(layer.Path, "ver4.5.7.25056", "fixture-marker")).To(BeARegularFile())
+		layer, err = j.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(filepath.Join(layer.Path, "javaagent.jar")).To(BeARegularFile())
+		Expect(filepath.Join(layer.Path, "provenance.properties")).NotTo(BeAnExistingFile())
 	})
 
-	context("$BP_TOMCAT_EXT_CONF_STRIP", func() {
+	context("$BP_APPD_JAVA_SLIM", func() {
 		it.Before(func() {
-			Expect(os.Setenv("BP_APPD_EXT_CONF_STRIP", "1")).To(Succeed())
+			t.Setenv("BP_APPD_JAVA_SLIM", "true")
 		})
 
-		it.After(func() {
-			Expect(os.Unsetenv("BP_APPD_EXT_CONF_STRIP")).To(Succeed())
-		})
-
-		it("contributes external configuration with directory", func() {
-			Expect(os.MkdirAll(filepath.Join(ctx.Buildpack.Path, "resources"), 0755)).To(Succeed())
-			Expect(ioutil.WriteFile(filepath.Join(ctx.Buildpack.Path, "resources", "app-agent-config.xml"), []byte{}, 0644)).
-				To(Succeed())
-			Expect(ioutil.WriteFile(filepath.Join(ctx.Buildpack.Path, "resources", "custom-activity-correlation.xml"), []byte{}, 0644)).
-				To(Succeed())
-			Expect(ioutil.WriteFile(filepath.Join(ctx.Buildpack.Path, "resources", "log4j2.xml"), []byte{}, 0644)).
-				To(Succeed())
-
-			agentDep := libpak.BuildpackDependency{
+		it("prunes unused files", func() {
+			dep := libpak.BuildpackDependency{
 				ID:     "appdynamics-java",
-				URI:    "https://localhost/stub-appdynamics-agent.zip",
-				SHA256: "ee23306ce5f7086219c1876652ed323970ebc249f21d1c79b737ac1120284bbf",
-			}
-			externalConfigurationDep := libpak.BuildpackDependency{
-				ID:     "appdynamics-external-configuration",
-				URI:    "https://localhost/stub-external-configuration-with-directory.tar.gz",
-				SHA256: "060818cbcdc2008563f0f9e2428ecf4a199a5821c5b8b1dcd11a67666c1e2cd6",
+				URI:    "https://localhost/stub-appdynamics-agent-full.zip",
+				SHA256: "37dd6fe8791333784b645cb9ca4050e1598f389a3232295d85c7cf53bf3d50ea",
 			}
 			dc := libpak.DependencyCache{CachePath: "testdata"}
 
-			j, bomEntries := appd.NewJavaAgent(ctx.Buildpack.Path, agentDep, libpak.ConfigurationResolver{}, &externalConfigurationDep, dc)
-			Expect(bomEntries).To(HaveLen(2))
-			Expect(bomEntries[0].Name).To(Equal("appdynamics-java"))
-			Expect(bomEntries[0].Metadata["layer"]).To(Equal("appdynamics-java"))
-			Expect(bomEntries[0].Launch).To(BeTrue())
-			Expect(bomEntries[0].Build).To(BeFalse())
-			Expect(bomEntries[1].Name).To(Equal("appdynamics-external-configuration"))
-			Expect(bomEntries[1].Metadata["layer"]).To(Equal("appdynamics-java"))
-			Expect(bomEntries[1].Launch).To(BeTrue())
-			Expect(bomEntries[1].Build).To(BeFalse())
+			j, _ := appd.NewJavaAgent(dep, libpak.ConfigurationResolver{}, dc)
+			Expect(j.LayerContributor.ExpectedMetadata).To(HaveKeyWithValue("slim", appd.JavaAgentSlimAllowList))
 
 			layer, err := ctx.Layers.Layer("test-layer")
 			Expect(err).NotTo(HaveOccurred())
@@ -180,9 +146,103 @@ func testJavaAgent(t *testing.T, context spec.G, it spec.S) {
 			layer, err = j.Contribute(layer)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(filepath.Join(layer.Path, "ver4.5.7.25056", "fixture-marker")).To(BeARegularFile())
+			v := filepath.Join(layer.Path, "ver4.5.7.25056")
+			Expect(filepath.Join(layer.Path, "javaagent.jar")).To(BeARegularFile())
+			Expect(filepath.Join(layer.Path, "LICENSE.txt")).To(BeARegularFile())
+			Expect(filepath.Join(v, "javaagent.jar")).To(BeARegularFile())
+			Expect(filepath.Join(v, "conf", "logging", "log4j2.xml")).To(BeARegularFile())
+			Expect(filepath.Join(v, "lib", "tp", "log4j-core.jar")).To(BeARegularFile())
+			Expect(filepath.Join(v, "external-services", "netviz", "netviz.jar")).To(BeARegularFile())
+			Expect(filepath.Join(v, "multi-release", "multi.jar")).To(BeARegularFile())
+			Expect(filepath.Join(v, "sdk-plugins", "plugin.jar")).To(BeARegularFile())
+			Expect(filepath.Join(v, "logs")).To(BeADirectory())
+			Expect(filepath.Join(layer.Path, "readme.txt")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(v, "sdk")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(v, "utils")).NotTo(BeAnExistingFile())
+
+			var cycloneDX appd.CycloneDXDocument
+			b, err := ioutil.ReadFile(layer.SBOMPath(libcnb.CycloneDXJSON))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Unmarshal(b, &cycloneDX)).To(Succeed())
+			Expect(cycloneDX.Metadata.Properties).To(Equal([]appd.CycloneDXProperty{
+				{Name: "appdynamics:slim", Value: "true"},
+				{Name: "appdynamics:slim:pruned", Value: "readme.txt"},
+				{Name: "appdynamics:slim:pruned", Value: "ver4.5.7.25056/sdk"},
+				{Name: "appdynamics:slim:pruned", Value: "ver4.5.7.25056/utils"},
+			}))
 		})
+	})
 
+	context("VerifyAgent", func() {
+		var (
+			buffer *bytes.Buffer
+			j      appd.JavaAgent
+			layer  libcnb.Layer
+		)
+
+		it.Before(func() {
+			var err error
+
+			buffer = bytes.NewBuffer(nil)
+			j, _ = appd.NewJavaAgent(libpak.BuildpackDependency{URI: "test-uri", Version: "1.1.1"}, libpak.ConfigurationResolver{}, libpak.DependencyCache{})
+			j.Logger = bard.NewLogger(buffer)
+
+			layer, err = ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(layer.Path, "ver1.1.1.1", "conf"), 0755)).To(Succeed())
+		})
+
+		it("fails without javaagent.jar", func() {
+			Expect(j.VerifyAgent(layer)).To(MatchError(fmt.Sprintf(
+				"javaagent.jar not found in %s, test-uri does not appear to be an AppDynamics Java agent archive", layer.Path)))
+		})
+
+		it("fails without version directory", func() {
+			Expect(writeJar(filepath.Join(layer.Path, "javaagent.jar"), "Implementation-Version: Server Agent #1.1.1.1 v1.1.1 GA")).To(Succeed())
+			Expect(os.RemoveAll(filepath.Join(layer.Path, "ver1.1.1.1", "conf"))).To(Succeed())
+
+			Expect(j.VerifyAgent(layer)).To(MatchError(ContainSubstring("test-uri does not appear to be an AppDynamics Java agent archive")))
+		})
+
+		it("verifies version", func() {
+			Expect(writeJar(filepath.Join(layer.Path, "javaagent.jar"), "Implementation-Version: Server Agent #1.1.1.1 v1.1.1 GA")).To(Succeed())
+
+			Expect(j.VerifyAgent(layer)).To(Succeed())
+			Expect(buffer.String()).To(ContainSubstring("Verified agent version 1.1.1.1"))
+		})
+
+		it("warns on version mismatch", func() {
+			Expect(writeJar(filepath.Join(layer.Path, "javaagent.jar"), "Implementation-Version: Server Agent #2.2.2.2 v2.2.2 GA")).To(Succeed())
+
+			Expect(j.VerifyAgent(layer)).To(Succeed())
+			Expect(buffer.String()).To(ContainSubstring("agent version 2.2.2.2 does not match expected version 1.1.1"))
+		})
+
+		it("warns without manifest version", func() {
+			Expect(writeJar(filepath.Join(layer.Path, "javaagent.jar"), "Created-By: test")).To(Succeed())
+
+			Expect(j.VerifyAgent(layer)).To(Succeed())
+			Expect(buffer.String()).To(ContainSubstring("no Implementation-Version in manifest"))
+		})
 	})
+}
+
+func writeJar(path string, manifest string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	z := zip.NewWriter(out)
+	w, err := z.Create("META-INF/MANIFEST.MF")
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "Manifest-Version: 1.0\n%s\n", manifest); err != nil {
+		return err
+	}
 
+	return z.Close()
 }
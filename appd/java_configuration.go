@@ -0,0 +1,324 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/heroku/color"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/crush"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+// JavaConfiguration contributes the agent configuration directory to a layer separate from the agent binaries so that
+// configuration changes do not require the agent to be expanded again. The directory starts as a copy of the agent's
+// own conf directory, overlaid with the buildpack-provided and external configuration.
+type JavaConfiguration struct {
+	AgentDependency                  libpak.BuildpackDependency
+	BuildpackPath                    string
+	ConfigurationResolver            libpak.ConfigurationResolver
+	DependencyCache                  libpak.DependencyCache
+	ExternalConfigurationCredentials *Credentials
+	ExternalConfigurationDependency  *libpak.BuildpackDependency
+	LayerContributor                 libpak.LayerContributor
+	Logger                           bard.Logger
+	SignatureVerifier                *SignatureVerifier
+}
+
+func NewJavaConfiguration(buildpackPath string, agentDependency libpak.BuildpackDependency, configurationResolver libpak.ConfigurationResolver, externalConfigurationDependency *libpak.BuildpackDependency, cache libpak.DependencyCache) (JavaConfiguration, []libcnb.BOMEntry) {
+	strip, _ := configurationResolver.Resolve("BP_APPD_EXT_CONF_STRIP")
+
+	metadata := map[string]interface{}{
+		"agent": agentDependency,
+	}
+
+	if s, ok := configurationResolver.Resolve("BP_APPD_JAVA_VERSION_DIR"); ok {
+		metadata["version-directory"] = s
+	}
+
+	if externalConfigurationDependency != nil {
+		metadata["external-configuration"] = *externalConfigurationDependency
+		metadata["strip"] = strip
+		metadata["require-verification"] = configurationResolver.ResolveBool("BP_APPD_EXT_CONF_REQUIRE_VERIFICATION")
+	}
+
+	j := JavaConfiguration{
+		AgentDependency:                 agentDependency,
+		BuildpackPath:                   buildpackPath,
+		ConfigurationResolver:           configurationResolver,
+		DependencyCache:                 cache,
+		ExternalConfigurationDependency: externalConfigurationDependency,
+		LayerContributor: libpak.NewLayerContributor(
+			"AppDynamics Java Agent Configuration",
+			metadata,
+			libcnb.LayerTypes{Launch: true},
+		),
+	}
+
+	var bomEntries []libcnb.BOMEntry
+	if externalConfigurationDependency != nil {
+		entry := externalConfigurationDependency.AsBOMEntry()
+		entry.Metadata["layer"] = j.Name()
+		entry.Launch = true
+		bomEntries = append(bomEntries, entry)
+	}
+
+	return j, bomEntries
+}
+
+func (j JavaConfiguration) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	j.LayerContributor.Logger = j.Logger
+
+	// the verification key is resolved after the contributor is created, so that rotating the key invalidates the layer
+	if m, ok := j.LayerContributor.ExpectedMetadata.(map[string]interface{}); ok && j.SignatureVerifier != nil {
+		metadata := make(map[string]interface{}, len(m)+1)
+		for k, v := range m {
+			metadata[k] = v
+		}
+		metadata["verification-key"] = j.SignatureVerifier.Fingerprint()
+		j.LayerContributor.ExpectedMetadata = metadata
+	}
+
+	return j.LayerContributor.Contribute(layer, func() (libcnb.Layer, error) {
+		if err := j.ContributeConfiguration(layer); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to contribute configuration\n%w", err)
+		}
+
+		if j.ExternalConfigurationDependency != nil {
+			if err := j.ContributeExternalConfiguration(layer); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to contribute external configuration\n%w", err)
+			}
+
+			if err := writeDependencySBOM(j.Logger, layer, []libpak.BuildpackDependency{*j.ExternalConfigurationDependency}); err != nil {
+				return libcnb.Layer{}, err
+			}
+		}
+
+		layer.LaunchEnvironment.Appendf("JAVA_TOOL_OPTIONS", " ",
+			"-Dappdynamics.agent.conf.dir=%s", filepath.Join(layer.Path, "conf"))
+
+		return layer, nil
+	})
+}
+
+func (j JavaConfiguration) ContributeConfiguration(layer libcnb.Layer) error {
+	v, err := VersionDirectory(libcnb.Layer{Path: j.AgentLayerPath(layer)}, j.ConfigurationResolver)
+	if err != nil {
+		return fmt.Errorf("unable to determine version directory\n%w", err)
+	}
+
+	j.Logger.Bodyf("Copying %s/conf to %s/conf", v, layer.Path)
+	file := filepath.Join(layer.Path, "conf")
+	if err := sherpa.CopyDir(filepath.Join(v, "conf"), file); err != nil {
+		return fmt.Errorf("unable to copy agent configuration\n%w", err)
+	}
+
+	j.Logger.Bodyf("Copying app-agent-config.xml to %s/conf", layer.Path)
+	file = filepath.Join(j.BuildpackPath, "resources", "app-agent-config.xml")
+	in, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("unable to open %s\n%w", file, err)
+	}
+	defer in.Close()
+
+	file = filepath.Join(layer.Path, "conf", "app-agent-config.xml")
+	if err := sherpa.CopyFile(in, file); err != nil {
+		return fmt.Errorf("unable to copy %s to %s\n%w", in.Name(), file, err)
+	}
+
+	j.Logger.Bodyf("Copying custom-activity-correlation.xml to %s/conf", layer.Path)
+	file = filepath.Join(j.BuildpackPath, "resources", "custom-activity-correlation.xml")
+	in, err = os.Open(file)
+	if err != nil {
+		return fmt.Errorf("unable to open %s\n%w", file, err)
+	}
+	defer in.Close()
+
+	file = filepath.Join(layer.Path, "conf", "custom-activity-correlation.xml")
+	if err := sherpa.CopyFile(in, file); err != nil {
+		return fmt.Errorf("unable to copy %s to %s\n%w", in.Name(), file, err)
+	}
+
+	file = filepath.Join(layer.Path, "conf", "logging")
+	if err := os.MkdirAll(file, 0755); err != nil {
+		return fmt.Errorf("unable to create directory %s\n%w", file, err)
+	}
+
+	j.Logger.Bodyf("Copying log4j2.xml to %s/conf/logging", layer.Path)
+	file = filepath.Join(j.BuildpackPath, "resources", "log4j2.xml")
+	in, err = os.Open(file)
+	if err != nil {
+		return fmt.Errorf("unable to open %s\n%w", file, err)
+	}
+	defer in.Close()
+
+	file = filepath.Join(layer.Path, "conf", "logging", "log4j2.xml")
+	if err := sherpa.CopyFile(in, file); err != nil {
+		return fmt.Errorf("unable to copy %s to %s\n%w", in.Name(), file, err)
+	}
+
+	return nil
+}
+
+func (j JavaConfiguration) ContributeExternalConfiguration(layer libcnb.Layer) error {
+	j.Logger.Header(color.BlueString("%s %s", j.ExternalConfigurationDependency.Name, j.ExternalConfigurationDependency.Version))
+
+	var mods []libpak.RequestModifierFunc
+	if j.ExternalConfigurationCredentials != nil {
+		j.Logger.Body("Using credentials to download external configuration")
+		mods = append(mods, j.ExternalConfigurationCredentials.RequestModifier())
+	}
+
+	artifact, err := j.DependencyCache.Artifact(*j.ExternalConfigurationDependency, mods...)
+	if err != nil {
+		return fmt.Errorf("unable to get dependency %s\n%w", j.ExternalConfigurationDependency.ID, err)
+	}
+	defer artifact.Close()
+
+	if err := j.VerifyExternalConfiguration(artifact, mods...); err != nil {
+		return fmt.Errorf("unable to verify external configuration\n%w", err)
+	}
+
+	if _, err := artifact.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("unable to rewind %s\n%w", artifact.Name(), err)
+	}
+
+	j.Logger.Bodyf("Expanding to %s", layer.Path)
+
+	c := 0
+	if s, ok := j.ConfigurationResolver.Resolve("BP_APPD_EXT_CONF_STRIP"); ok {
+		if c, err = strconv.Atoi(s); err != nil {
+			return fmt.Errorf("unable to parse %s to integer\n%w", s, err)
+		}
+	}
+
+	if err := crush.ExtractTarGz(artifact, layer.Path, c); err != nil {
+		return fmt.Errorf("unable to expand external configuration\n%w", err)
+	}
+
+	return nil
+}
+
+// VerifyExternalConfiguration verifies the detached signature of the external configuration if a verification key is
+// configured. The signature is downloaded from $BP_APPD_EXT_CONF_SIGNATURE_URI, or from $BP_APPD_EXT_CONF_URI with a
+// .sig (cosign) or .asc (GPG) extension. If $BP_APPD_EXT_CONF_REQUIRE_VERIFICATION is set, an archive without either a
+// SHA256 or a verified signature fails the build.
+func (j JavaConfiguration) VerifyExternalConfiguration(artifact *os.File, mods ...libpak.RequestModifierFunc) error {
+	if j.SignatureVerifier == nil {
+		switch {
+		case j.ExternalConfigurationDependency.SHA256 != "":
+			return nil
+		case j.ConfigurationResolver.ResolveBool("BP_APPD_EXT_CONF_REQUIRE_VERIFICATION"):
+			return fmt.Errorf("$BP_APPD_EXT_CONF_REQUIRE_VERIFICATION is set but neither $BP_APPD_EXT_CONF_SHA256 nor a verification key is configured for %s",
+				j.ExternalConfigurationDependency.URI)
+		default:
+			j.Logger.Bodyf("%s: %s is not verified, set $BP_APPD_EXT_CONF_SHA256 or bind a verification key",
+				color.YellowString("Warning"), j.ExternalConfigurationDependency.URI)
+			return nil
+		}
+	}
+
+	uri, ok := j.ConfigurationResolver.Resolve("BP_APPD_EXT_CONF_SIGNATURE_URI")
+	if !ok {
+		uri = SignatureURI(j.ExternalConfigurationDependency.URI, j.SignatureVerifier.Extension())
+	}
+
+	b, err := j.Signature(uri, mods...)
+	if err != nil {
+		return fmt.Errorf("unable to get signature %s\n%w", uri, err)
+	}
+
+	if err := j.SignatureVerifier.Verify(artifact, b); err != nil {
+		return fmt.Errorf("unable to verify %s with signature %s\n%w", j.ExternalConfigurationDependency.URI, uri, err)
+	}
+
+	j.Logger.Bodyf("Verified signature %s", uri)
+	return nil
+}
+
+// Signature downloads the detached signature at uri with the same request modifiers as the external configuration. It
+// is requested directly rather than through the dependency cache, as the signature has no SHA256 to cache it by.
+func (j JavaConfiguration) Signature(uri string, mods ...libpak.RequestModifierFunc) ([]byte, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s\n%w", uri, err)
+	}
+
+	if u.Scheme == "file" {
+		return os.ReadFile(u.Path)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request\n%w", err)
+	}
+	if j.DependencyCache.UserAgent != "" {
+		req.Header.Set("User-Agent", j.DependencyCache.UserAgent)
+	}
+
+	for _, m := range mods {
+		if req, err = m(req); err != nil {
+			return nil, fmt.Errorf("unable to modify request\n%w", err)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to request %s\n%w", u.Redacted(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("could not download %s: %d", u.Redacted(), resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// AgentLayerPath returns the path of the agent layer contributed alongside the configuration layer.
+func (JavaConfiguration) AgentLayerPath(layer libcnb.Layer) string {
+	return filepath.Join(filepath.Dir(layer.Path), JavaAgent{}.Name())
+}
+
+func (JavaConfiguration) Name() string {
+	return "appdynamics-java-configuration"
+}
+
+// SignatureURI returns the URI of the detached signature of uri by appending extension to its path, so that the query
+// of a presigned URI is preserved.
+func SignatureURI(uri string, extension string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Opaque != "" {
+		return uri + extension
+	}
+
+	u.Path += extension
+	if u.RawPath != "" {
+		u.RawPath += extension
+	}
+
+	return u.String()
+}
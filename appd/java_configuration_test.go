@@ -0,0 +1,337 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/appd"
+)
+
+func testJavaConfiguration(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		ctx      libcnb.BuildContext
+		agentDep libpak.BuildpackDependency
+		dc       libpak.DependencyCache
+	)
+
+	it.Before(func() {
+		var err error
+
+		ctx.Buildpack.Path, err = ioutil.TempDir("", "java-configuration-buildpack")
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx.Layers.Path, err = ioutil.TempDir("", "java-configuration-layers")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(ctx.Buildpack.Path, "resources"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(ctx.Buildpack.Path, "resources", "app-agent-config.xml"), []byte("test-app-agent-config"), 0644)).
+			To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(ctx.Buildpack.Path, "resources", "custom-activity-correlation.xml"), []byte{}, 0644)).
+			To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(ctx.Buildpack.Path, "resources", "log4j2.xml"), []byte{}, 0644)).
+			To(Succeed())
+
+		agentDep = libpak.BuildpackDependency{
+			ID:     "appdynamics-java",
+			URI:    "https://localhost/stub-appdynamics-agent.zip",
+			SHA256: "ee23306ce5f7086219c1876652ed323970ebc249f21d1c79b737ac1120284bbf",
+		}
+		dc = libpak.DependencyCache{CachePath: "testdata"}
+
+		ja, _ := appd.NewJavaAgent(agentDep, libpak.ConfigurationResolver{}, dc)
+		layer, err := ctx.Layers.Layer(ja.Name())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = ja.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(ctx.Buildpack.Path)).To(Succeed())
+		Expect(os.RemoveAll(ctx.Layers.Path)).To(Succeed())
+	})
+
+	it("contributes configuration", func() {
+		j, bomEntries := appd.NewJavaConfiguration(ctx.Buildpack.Path, agentDep, libpak.ConfigurationResolver{}, nil, dc)
+		Expect(bomEntries).To(BeEmpty())
+
+		layer, err := ctx.Layers.Layer(j.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = j.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(layer.Launch).To(BeTrue())
+		Expect(layer.Cache).To(BeFalse())
+		Expect(ioutil.ReadFile(filepath.Join(layer.Path, "conf", "app-agent-config.xml"))).To(Equal([]byte("test-app-agent-config")))
+		Expect(filepath.Join(layer.Path, "conf", "custom-activity-correlation.xml")).To(BeARegularFile())
+		Expect(filepath.Join(layer.Path, "conf", "logging", "log4j2.xml")).To(BeARegularFile())
+		Expect(layer.LaunchEnvironment["JAVA_TOOL_OPTIONS.delim"]).To(Equal(" "))
+		Expect(layer.LaunchEnvironment["JAVA_TOOL_OPTIONS.append"]).To(Equal(fmt.Sprintf("-Dappdynamics.agent.conf.dir=%s",
+			filepath.Join(layer.Path, "conf"))))
+		Expect(layer.SBOMPath(libcnb.SyftJSON)).NotTo(BeAnExistingFile())
+		Expect(layer.SBOMPath(libcnb.CycloneDXJSON)).NotTo(BeAnExistingFile())
+	})
+
+	it("contributes external configuration", func() {
+		externalConfigurationDep := libpak.BuildpackDependency{
+			ID:     "appdynamics-external-configuration",
+			URI:    "https://localhost/stub-external-configuration.tar.gz",
+			SHA256: "22e708cfd301430cbcf8d1c2289503d8288d50df519ff4db7cca0ff9fe83c324",
+		}
+
+		j, bomEntries := appd.NewJavaConfiguration(ctx.Buildpack.Path, agentDep, libpak.ConfigurationResolver{}, &externalConfigurationDep, dc)
+		Expect(bomEntries).To(HaveLen(1))
+		Expect(bomEntries[0].Name).To(Equal("appdynamics-external-configuration"))
+		Expect(bomEntries[0].Metadata["layer"]).To(Equal("appdynamics-java-configuration"))
+		Expect(bomEntries[0].Launch).To(BeTrue())
+		Expect(bomEntries[0].Build).To(BeFalse())
+
+		layer, err := ctx.Layers.Layer(j.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = j.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(filepath.Join(layer.Path, "fixture-marker")).To(BeARegularFile())
+		Expect(layer.SBOMPath(libcnb.SyftJSON)).To(BeARegularFile())
+		Expect(layer.SBOMPath(libcnb.CycloneDXJSON)).To(BeARegularFile())
+	})
+
+	it("contributes external configuration with credentials", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer test-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.ServeFile(w, r, filepath.Join("testdata", "22e708cfd301430cbcf8d1c2289503d8288d50df519ff4db7cca0ff9fe83c324", "stub-external-configuration.tar.gz"))
+		}))
+		defer server.Close()
+
+		externalConfigurationDep := libpak.BuildpackDependency{
+			ID:  "appdynamics-external-configuration",
+			URI: fmt.Sprintf("%s/stub-external-configuration.tar.gz", server.URL),
+		}
+		dc.DownloadPath = t.TempDir()
+
+		j, _ := appd.NewJavaConfiguration(ctx.Buildpack.Path, agentDep, libpak.ConfigurationResolver{}, &externalConfigurationDep, dc)
+		j.ExternalConfigurationCredentials = &appd.Credentials{Token: "test-token"}
+
+		layer, err := ctx.Layers.Layer(j.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = j.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(filepath.Join(layer.Path, "fixture-marker")).To(BeARegularFile())
+		Expect(fmt.Sprintf("%v", layer.Metadata)).NotTo(ContainSubstring("test-token"))
+	})
+
+	context("$BP_APPD_EXT_CONF_STRIP", func() {
+		it.Before(func() {
+			t.Setenv("BP_APPD_EXT_CONF_STRIP", "1")
+		})
+
+		it("contributes external configuration with directory", func() {
+			externalConfigurationDep := libpak.BuildpackDependency{
+				ID:     "appdynamics-external-configuration",
+				URI:    "https://localhost/stub-external-configuration-with-directory.tar.gz",
+				SHA256: "060818cbcdc2008563f0f9e2428ecf4a199a5821c5b8b1dcd11a67666c1e2cd6",
+			}
+
+			j, _ := appd.NewJavaConfiguration(ctx.Buildpack.Path, agentDep, libpak.ConfigurationResolver{}, &externalConfigurationDep, dc)
+			Expect(j.LayerContributor.ExpectedMetadata).To(HaveKeyWithValue("strip", "1"))
+
+			layer, err := ctx.Layers.Layer(j.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = j.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, "fixture-marker")).To(BeARegularFile())
+		})
+	})
+
+	context("signature verification", func() {
+		var (
+			externalConfigurationDep libpak.BuildpackDependency
+			signature                string
+		)
+
+		it.Before(func() {
+			in, err := ioutil.ReadFile(filepath.Join("testdata", "22e708cfd301430cbcf8d1c2289503d8288d50df519ff4db7cca0ff9fe83c324", "stub-external-configuration.tar.gz"))
+			Expect(err).NotTo(HaveOccurred())
+
+			dir := t.TempDir()
+			file := filepath.Join(dir, "stub-external-configuration.tar.gz")
+			Expect(ioutil.WriteFile(file, in, 0644)).To(Succeed())
+
+			externalConfigurationDep = libpak.BuildpackDependency{
+				ID:  "appdynamics-external-configuration",
+				URI: fmt.Sprintf("file://%s", file),
+			}
+			signature = fmt.Sprintf("%s.sig", file)
+			dc.DownloadPath = t.TempDir()
+		})
+
+		it("fails when verification is required", func() {
+			t.Setenv("BP_APPD_EXT_CONF_REQUIRE_VERIFICATION", "true")
+
+			j, _ := appd.NewJavaConfiguration(ctx.Buildpack.Path, agentDep, libpak.ConfigurationResolver{}, &externalConfigurationDep, dc)
+			Expect(j.LayerContributor.ExpectedMetadata).To(HaveKeyWithValue("require-verification", true))
+
+			layer, err := ctx.Layers.Layer(j.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = j.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("$BP_APPD_EXT_CONF_REQUIRE_VERIFICATION is set but neither")))
+			Expect(filepath.Join(layer.Path, "fixture-marker")).NotTo(BeAnExistingFile())
+		})
+
+		it("contributes external configuration with verified signature", func() {
+			t.Setenv("BP_APPD_EXT_CONF_REQUIRE_VERIFICATION", "true")
+
+			in, err := ioutil.ReadFile(filepath.Join("testdata", "22e708cfd301430cbcf8d1c2289503d8288d50df519ff4db7cca0ff9fe83c324", "stub-external-configuration.tar.gz"))
+			Expect(err).NotTo(HaveOccurred())
+			sig, key, err := cosignSign(in)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(signature, sig, 0644)).To(Succeed())
+
+			j, _ := appd.NewJavaConfiguration(ctx.Buildpack.Path, agentDep, libpak.ConfigurationResolver{}, &externalConfigurationDep, dc)
+			j.SignatureVerifier = &appd.SignatureVerifier{CosignKey: key}
+
+			layer, err := ctx.Layers.Layer(j.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = j.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, "fixture-marker")).To(BeARegularFile())
+			Expect(layer.Metadata).To(HaveKeyWithValue("verification-key", j.SignatureVerifier.Fingerprint()))
+			Expect(j.LayerContributor.ExpectedMetadata).NotTo(HaveKey("verification-key"))
+		})
+
+		it("downloads signature with credentials", func() {
+			in, err := ioutil.ReadFile(filepath.Join("testdata", "22e708cfd301430cbcf8d1c2289503d8288d50df519ff4db7cca0ff9fe83c324", "stub-external-configuration.tar.gz"))
+			Expect(err).NotTo(HaveOccurred())
+			sig, key, err := cosignSign(in)
+			Expect(err).NotTo(HaveOccurred())
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer test-token" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				switch r.URL.Path {
+				case "/stub-external-configuration.tar.gz":
+					_, _ = w.Write(in)
+				case "/stub-external-configuration.tar.gz.sig":
+					_, _ = w.Write(sig)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			buffer := bytes.NewBuffer(nil)
+			dc.Logger = bard.NewLogger(buffer)
+			externalConfigurationDep.URI = fmt.Sprintf("%s/stub-external-configuration.tar.gz", server.URL)
+
+			j, _ := appd.NewJavaConfiguration(ctx.Buildpack.Path, agentDep, libpak.ConfigurationResolver{}, &externalConfigurationDep, dc)
+			j.ExternalConfigurationCredentials = &appd.Credentials{Token: "test-token"}
+			j.SignatureVerifier = &appd.SignatureVerifier{CosignKey: key}
+
+			layer, err := ctx.Layers.Layer(j.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = j.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(strings.Count(buffer.String(), "Dependency has no SHA256")).To(Equal(1))
+			Expect(buffer.String()).NotTo(ContainSubstring(".sig"))
+		})
+
+		it("appends signature extension to the path of the URI", func() {
+			Expect(appd.SignatureURI("https://test-bucket/test/config.tar.gz?X-Amz-Signature=test&X-Amz-Expires=300", ".sig")).
+				To(Equal("https://test-bucket/test/config.tar.gz.sig?X-Amz-Signature=test&X-Amz-Expires=300"))
+			Expect(appd.SignatureURI("file:///test/config.tar.gz", ".asc")).To(Equal("file:///test/config.tar.gz.asc"))
+		})
+
+		it("fails with invalid signature", func() {
+			sig, key, err := cosignSign([]byte("other-content"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(signature, sig, 0644)).To(Succeed())
+
+			j, _ := appd.NewJavaConfiguration(ctx.Buildpack.Path, agentDep, libpak.ConfigurationResolver{}, &externalConfigurationDep, dc)
+			j.SignatureVerifier = &appd.SignatureVerifier{CosignKey: key}
+
+			layer, err := ctx.Layers.Layer(j.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = j.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("invalid signature")))
+			Expect(filepath.Join(layer.Path, "fixture-marker")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	context("multiple version directories", func() {
+		it.Before(func() {
+			agentDep = libpak.BuildpackDependency{
+				ID:     "appdynamics-java",
+				URI:    "https://localhost/stub-appdynamics-agent-multiple-versions.zip",
+				SHA256: "ca3462517fa0cd945740741dd461c91a03d78b7e34b0593149dbd59ea1709e68",
+			}
+
+			ja, _ := appd.NewJavaAgent(agentDep, libpak.ConfigurationResolver{}, dc)
+			layer, err := ctx.Layers.Layer(ja.Name())
+			Expect(err).NotTo(HaveOccurred())
+			_, err = ja.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("contributes configuration from highest version directory", func() {
+			j, _ := appd.NewJavaConfiguration(ctx.Buildpack.Path, agentDep, libpak.ConfigurationResolver{}, nil, dc)
+
+			layer, err := ctx.Layers.Layer(j.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = j.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(j.AgentLayerPath(layer), "ver4.5.7.25056", "logs")).To(BeADirectory())
+			Expect(filepath.Join(j.AgentLayerPath(layer), "ver4.5.6.12345", "logs")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(layer.Path, "conf", "app-agent-config.xml")).To(BeARegularFile())
+		})
+	})
+}
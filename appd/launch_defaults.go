@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd
+
+import (
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/helper"
+)
+
+// BindingDefaultsAllowList are the binding keys that are not secret and may be written to the image as launch
+// defaults when $BP_APPD_BINDING_DEFAULTS is set.
+var BindingDefaultsAllowList = []string{
+	"agent-account-name",
+	"agent-application-name",
+	"agent-tier-name",
+	"controller-host-name",
+	"controller-port",
+	"controller-ssl-enabled",
+}
+
+// BindingDefaults returns the launch defaults for the allow-listed keys of a binding, after normalising the keys. Values that the helper would
+// export as file paths are skipped.
+func BindingDefaults(binding libcnb.Binding) map[string]string {
+	defaults := map[string]string{}
+	keys := helper.NormaliseKeys(binding.Secret)
+
+	for _, k := range BindingDefaultsAllowList {
+		raw, ok := keys[k]
+		if !ok {
+			continue
+		}
+
+		v := binding.Secret[raw]
+		if strings.Contains(strings.TrimSpace(v), "\n") {
+			continue
+		}
+
+		defaults[helper.EnvironmentName(k)] = v
+	}
+
+	return defaults
+}
+
+// LaunchDefaults contributes the launch defaults to a layer of their own, so that the cached agent layers are never
+// modified after they are contributed and a change to the defaults only replaces this layer. Each default is also
+// recorded with helper.DefaultName so that the helper does not mistake it for an explicit value. If Deactivate is set,
+// helper.DeactivateMarker is also recorded so that the helper deactivates the agent if it is not configured at launch.
+type LaunchDefaults struct {
+	Deactivate       bool
+	Defaults         map[string]string
+	LayerContributor libpak.LayerContributor
+	Logger           bard.Logger
+}
+
+func NewLaunchDefaults(defaults map[string]string, deactivate bool) LaunchDefaults {
+	return LaunchDefaults{
+		Deactivate: deactivate,
+		Defaults:   defaults,
+		LayerContributor: libpak.NewLayerContributor(
+			"AppDynamics Launch Defaults",
+			map[string]interface{}{"deactivate": deactivate, "defaults": defaults},
+			libcnb.LayerTypes{Launch: true},
+		),
+	}
+}
+
+func (l LaunchDefaults) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	l.LayerContributor.Logger = l.Logger
+
+	return l.LayerContributor.Contribute(layer, func() (libcnb.Layer, error) {
+		for k, v := range l.Defaults {
+			layer.LaunchEnvironment.Default(k, v)
+			layer.LaunchEnvironment.Default(helper.DefaultName(k), v)
+		}
+
+		if l.Deactivate {
+			layer.LaunchEnvironment.Default(helper.DeactivateMarker, "true")
+		}
+
+		return layer, nil
+	})
+}
+
+func (LaunchDefaults) Name() string {
+	return "appdynamics-launch-defaults"
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/appd"
+)
+
+func testLaunchDefaults(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		ctx libcnb.BuildContext
+	)
+
+	it.Before(func() {
+		ctx.Layers.Path = t.TempDir()
+	})
+
+	it("returns allow-listed binding values", func() {
+		Expect(appd.BindingDefaults(libcnb.Binding{
+			Secret: map[string]string{
+				"agent-account-access-key": "test-access-key",
+				"agent-node-name":          "test-node",
+				"agent-tier-name":          "test-tier",
+				"controller-port":          "443",
+				"controller-host-name":     "test-host\nother-host\n",
+			},
+		})).To(Equal(map[string]string{
+			"APPDYNAMICS_AGENT_TIER_NAME": "test-tier",
+			"APPDYNAMICS_CONTROLLER_PORT": "443",
+		}))
+	})
+
+	it("normalises binding keys", func() {
+		Expect(appd.BindingDefaults(libcnb.Binding{
+			Secret: map[string]string{
+				"APPDYNAMICS_AGENT_ACCOUNT_NAME": "test-account-name",
+				"access-key":                     "test-access-key",
+				"host":                           "test-host",
+			},
+		})).To(Equal(map[string]string{
+			"APPDYNAMICS_AGENT_ACCOUNT_NAME":   "test-account-name",
+			"APPDYNAMICS_CONTROLLER_HOST_NAME": "test-host",
+		}))
+	})
+
+	it("contributes launch defaults", func() {
+		l := appd.NewLaunchDefaults(map[string]string{"APPDYNAMICS_AGENT_TIER_NAME": "test-tier"}, false)
+		Expect(l.LayerContributor.ExpectedMetadata).To(Equal(map[string]interface{}{
+			"deactivate": false,
+			"defaults":   map[string]string{"APPDYNAMICS_AGENT_TIER_NAME": "test-tier"},
+		}))
+
+		layer, err := ctx.Layers.Layer(l.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = l.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(layer.Launch).To(BeTrue())
+		Expect(layer.Cache).To(BeFalse())
+		Expect(layer.LaunchEnvironment).To(Equal(libcnb.Environment{
+			"APPDYNAMICS_AGENT_TIER_NAME.default":      "test-tier",
+			"BPI_APPD_DEFAULT_AGENT_TIER_NAME.default": "test-tier",
+		}))
+	})
+
+	it("contributes deactivation marker", func() {
+		l := appd.NewLaunchDefaults(map[string]string{}, true)
+
+		layer, err := ctx.Layers.Layer(l.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		layer, err = l.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(layer.LaunchEnvironment).To(Equal(libcnb.Environment{
+			"BPI_APPD_DEACTIVATE_UNCONFIGURED.default": "true",
+		}))
+	})
+
+	it("replaces launch defaults from previous builds", func() {
+		layer, err := ctx.Layers.Layer("appdynamics-launch-defaults")
+		Expect(err).NotTo(HaveOccurred())
+		layer.Metadata = map[string]interface{}{"defaults": map[string]interface{}{"APPDYNAMICS_AGENT_NODE_NAME": "test-node"}}
+		env := filepath.Join(layer.Path, "env.launch")
+		Expect(os.MkdirAll(env, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(env, "APPDYNAMICS_AGENT_NODE_NAME.default"), []byte("test-node"), 0644)).To(Succeed())
+
+		layer, err = appd.NewLaunchDefaults(map[string]string{"APPDYNAMICS_AGENT_TIER_NAME": "test-tier"}, false).Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(filepath.Join(env, "APPDYNAMICS_AGENT_NODE_NAME.default")).NotTo(BeAnExistingFile())
+		Expect(layer.LaunchEnvironment).To(HaveKeyWithValue("APPDYNAMICS_AGENT_TIER_NAME.default", "test-tier"))
+	})
+}
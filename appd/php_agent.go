@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/paketo-buildpacks/libpak"
@@ -28,17 +30,34 @@ import (
 	"github.com/paketo-buildpacks/libpak/effect"
 )
 
+// PHPSAPIProcessTypes maps the PHP SAPIs that the agent can be scoped to onto the process type that runs them by default.
+var PHPSAPIProcessTypes = map[string]string{
+	"apache": "web",
+	"cli":    "task",
+	"fpm":    "web",
+}
+
 type PHPAgent struct {
-	Executor         effect.Executor
-	LayerContributor libpak.DependencyLayerContributor
-	Logger           bard.Logger
+	ConfigurationResolver libpak.ConfigurationResolver
+	Executor              effect.Executor
+	LayerContributor      libpak.DependencyLayerContributor
+	Logger                bard.Logger
 }
 
-func NewPHPAgent(dependency libpak.BuildpackDependency, cache libpak.DependencyCache) (PHPAgent, libcnb.BOMEntry) {
+func NewPHPAgent(dependency libpak.BuildpackDependency, configurationResolver libpak.ConfigurationResolver, cache libpak.DependencyCache) (PHPAgent, libcnb.BOMEntry) {
 	contributor, entry := libpak.NewDependencyLayer(dependency, cache, libcnb.LayerTypes{Launch: true})
+
+	if s, ok := configurationResolver.Resolve("BP_APPD_PHP_SAPIS"); ok {
+		contributor.ExpectedMetadata = map[string]interface{}{
+			"dependency": dependency,
+			"sapis":      s,
+		}
+	}
+
 	return PHPAgent{
-		Executor:         effect.NewExecutor(),
-		LayerContributor: contributor,
+		ConfigurationResolver: configurationResolver,
+		Executor:              effect.NewExecutor(),
+		LayerContributor:      contributor,
 	}, entry
 }
 
@@ -52,12 +71,20 @@ func (p PHPAgent) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
 			return libcnb.Layer{}, fmt.Errorf("unable to expand New Relic\n%w", err)
 		}
 
+		if err := writeCycloneDXSBOM(p.Logger, layer, []libpak.BuildpackDependency{p.LayerContributor.Dependency}); err != nil {
+			return libcnb.Layer{}, err
+		}
+
 		file := filepath.Join(layer.Path, "php.ini.d")
 		if err := os.MkdirAll(file, 0755); err != nil {
 			return libcnb.Layer{}, fmt.Errorf("unable to create %s\n%w", file, err)
 		}
 
-		layer.LaunchEnvironment.Prepend("PHP_INI_SCAN_DIR", string(os.PathListSeparator), file)
+		layer.LaunchEnvironment.Default("BPI_APPD_AGENT_PATH", layer.Path)
+
+		if err := p.ContributeScanDirectory(layer, file); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to configure PHP_INI_SCAN_DIR\n%w", err)
+		}
 
 		e, ok := os.LookupEnv("PHP_EXTENSION_DIR")
 		if !ok {
@@ -105,6 +132,68 @@ func (p PHPAgent) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
 	})
 }
 
+// ContributeScanDirectory prepends the agent's ini directory to $PHP_INI_SCAN_DIR. If $BP_APPD_PHP_SAPIS is set, the
+// directory is only added for the process types that run the listed SAPIs, otherwise it is added for all processes.
+func (p PHPAgent) ContributeScanDirectory(layer libcnb.Layer, directory string) error {
+	s, ok := p.ConfigurationResolver.Resolve("BP_APPD_PHP_SAPIS")
+	if !ok {
+		layer.LaunchEnvironment.Prepend("PHP_INI_SCAN_DIR", string(os.PathListSeparator), directory)
+		return nil
+	}
+
+	types, err := PHPProcessTypes(s)
+	if err != nil {
+		return fmt.Errorf("unable to parse $BP_APPD_PHP_SAPIS\n%w", err)
+	}
+
+	for _, t := range types {
+		p.Logger.Bodyf("Enabling agent for process type %s", t)
+		layer.LaunchEnvironment.ProcessPrepend(t, "PHP_INI_SCAN_DIR", string(os.PathListSeparator), directory)
+	}
+
+	return nil
+}
+
+// PHPProcessTypes returns the sorted, de-duplicated process types for a comma-separated list of SAPIs. Each entry is
+// either a SAPI name from PHPSAPIProcessTypes or <sapi>:<process-type> to override the default process type.
+func PHPProcessTypes(sapis string) ([]string, error) {
+	seen := map[string]bool{}
+	var types []string
+
+	for _, s := range strings.Split(sapis, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		sapi, t, custom := strings.Cut(s, ":")
+		sapi = strings.ToLower(strings.TrimSpace(sapi))
+
+		d, ok := PHPSAPIProcessTypes[sapi]
+		if !ok {
+			return nil, fmt.Errorf("unsupported SAPI %s", sapi)
+		}
+
+		if t = strings.TrimSpace(t); !custom {
+			t = d
+		} else if t == "" {
+			return nil, fmt.Errorf("no process type specified for SAPI %s", sapi)
+		}
+
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+
+	if len(types) == 0 {
+		return nil, fmt.Errorf("no SAPIs specified")
+	}
+
+	sort.Strings(types)
+	return types, nil
+}
+
 func (p PHPAgent) Name() string {
 	return p.LayerContributor.LayerName()
 }
@@ -72,7 +72,7 @@ func testPHPAgent(t *testing.T, context spec.G, it spec.S) {
 			}
 			dc := libpak.DependencyCache{CachePath: "testdata"}
 
-			j, _ := appd.NewPHPAgent(dep, dc)
+			j, _ := appd.NewPHPAgent(dep, libpak.ConfigurationResolver{}, dc)
 			j.Executor = executor
 			layer, err := ctx.Layers.Layer("test-layer")
 			Expect(err).NotTo(HaveOccurred())
@@ -97,13 +97,97 @@ func testPHPAgent(t *testing.T, context spec.G, it spec.S) {
 				"${APPDYNAMICS_AGENT_NODE_NAME}",
 			}))
 
+			Expect(layer.SBOMPath(libcnb.SyftJSON)).To(BeARegularFile())
+			Expect(layer.SBOMPath(libcnb.CycloneDXJSON)).To(BeARegularFile())
+
 			Expect(layer.LaunchEnvironment["PHP_INI_SCAN_DIR.delim"]).To(Equal(string(os.PathListSeparator)))
 			Expect(layer.LaunchEnvironment["PHP_INI_SCAN_DIR.prepend"]).To(Equal(filepath.Join(layer.Path, "php.ini.d")))
+			Expect(layer.LaunchEnvironment["BPI_APPD_AGENT_PATH.default"]).To(Equal(layer.Path))
 			Expect(ioutil.ReadFile(filepath.Join(layer.Path, "php.ini.d", "appdynamics_agent.ini"))).To(Equal([]byte(fmt.Sprintf(
 				`
 agent.controller.ssl.enabled = ${APPDYNAMICS_CONTROLLER_SSL_ENABLED}
 `))))
 		})
+
+		it("reuses restored layer without a directory", func() {
+			dep := libpak.BuildpackDependency{
+				URI:    "https://localhost/stub-appdynamics-agent.tar.bz2",
+				SHA256: "4918be522d0e00aa799d924266f00422ea059d7ee78177e1dde3335549433df7",
+			}
+			dc := libpak.DependencyCache{CachePath: "testdata"}
+
+			j, _ := appd.NewPHPAgent(dep, libpak.ConfigurationResolver{}, dc)
+			j.Executor = executor
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = j.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			metadata := layer.Metadata
+
+			Expect(os.RemoveAll(layer.Path)).To(Succeed())
+			Expect(ioutil.WriteFile(layer.Path+".toml", []byte{}, 0644)).To(Succeed())
+
+			layer, err = ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+			layer.Metadata = metadata
+
+			_, err = j.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(layer.Path).NotTo(BeAnExistingFile())
+			Expect(executor.Calls).To(HaveLen(1))
+		})
+
+		context("$BP_APPD_PHP_SAPIS", func() {
+			it.Before(func() {
+				t.Setenv("BP_APPD_PHP_SAPIS", "fpm,apache,cli:worker")
+			})
+
+			it("scopes PHP_INI_SCAN_DIR to SAPI process types", func() {
+				dep := libpak.BuildpackDependency{
+					URI:    "https://localhost/stub-appdynamics-agent.tar.bz2",
+					SHA256: "4918be522d0e00aa799d924266f00422ea059d7ee78177e1dde3335549433df7",
+				}
+				dc := libpak.DependencyCache{CachePath: "testdata"}
+
+				j, _ := appd.NewPHPAgent(dep, libpak.ConfigurationResolver{}, dc)
+				j.Executor = executor
+				Expect(j.LayerContributor.ExpectedMetadata).To(Equal(map[string]interface{}{
+					"dependency": dep,
+					"sapis":      "fpm,apache,cli:worker",
+				}))
+
+				layer, err := ctx.Layers.Layer("test-layer")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.MkdirAll(filepath.Join(layer.Path, "php.ini.d"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(layer.Path, "php.ini.d", "appdynamics_agent.ini"), []byte{}, 0644)).To(Succeed())
+
+				layer, err = j.Contribute(layer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(layer.LaunchEnvironment).NotTo(HaveKey("PHP_INI_SCAN_DIR.prepend"))
+				Expect(layer.LaunchEnvironment["web/PHP_INI_SCAN_DIR.prepend"]).To(Equal(filepath.Join(layer.Path, "php.ini.d")))
+				Expect(layer.LaunchEnvironment["worker/PHP_INI_SCAN_DIR.prepend"]).To(Equal(filepath.Join(layer.Path, "php.ini.d")))
+				Expect(layer.LaunchEnvironment).NotTo(HaveKey("task/PHP_INI_SCAN_DIR.prepend"))
+			})
+		})
+	})
+
+	context("PHPProcessTypes", func() {
+		it("uses default process types", func() {
+			Expect(appd.PHPProcessTypes("fpm, cli")).To(Equal([]string{"task", "web"}))
+		})
+
+		it("fails with unknown SAPI", func() {
+			_, err := appd.PHPProcessTypes("cgi")
+			Expect(err).To(MatchError("unsupported SAPI cgi"))
+		})
+
+		it("fails without process type", func() {
+			_, err := appd.PHPProcessTypes("fpm:")
+			Expect(err).To(MatchError("no process type specified for SAPI fpm"))
+		})
 	})
 
 }
@@ -0,0 +1,200 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/helper"
+)
+
+// Provenance describes the build that contributed an agent so that a node can be traced back to its image.
+type Provenance struct {
+	AgentVersion     string
+	BuildpackVersion string
+	Revision         string
+	Timestamp        time.Time
+}
+
+// NewProvenance returns the provenance of the current build. The revision is read from $BP_APPD_BUILD_REVISION, or
+// from the .git directory of the application, and the timestamp from $SOURCE_DATE_EPOCH if set.
+func NewProvenance(context libcnb.BuildContext, configurationResolver libpak.ConfigurationResolver) (Provenance, error) {
+	p := Provenance{
+		BuildpackVersion: context.Buildpack.Info.Version,
+		Timestamp:        time.Now().UTC(),
+	}
+
+	if s, ok := configurationResolver.Resolve("BP_APPD_BUILD_REVISION"); ok {
+		p.Revision = s
+	} else {
+		var err error
+		if p.Revision, err = GitRevision(context.Application.Path); err != nil {
+			return Provenance{}, fmt.Errorf("unable to determine git revision\n%w", err)
+		}
+	}
+
+	if s, ok := os.LookupEnv("SOURCE_DATE_EPOCH"); ok {
+		epoch, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return Provenance{}, fmt.Errorf("unable to parse $SOURCE_DATE_EPOCH\n%w", err)
+		}
+		p.Timestamp = time.Unix(epoch, 0).UTC()
+	}
+
+	return p, nil
+}
+
+// GitRevision returns the commit checked out in the .git directory of path, or an empty string if path is not a git
+// repository.
+func GitRevision(path string) (string, error) {
+	dir := filepath.Join(path, ".git")
+
+	if fi, err := os.Stat(dir); os.IsNotExist(err) || (err == nil && !fi.IsDir()) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("unable to stat %s\n%w", dir, err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "HEAD"))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("unable to read %s\n%w", filepath.Join(dir, "HEAD"), err)
+	}
+
+	head := strings.TrimSpace(string(b))
+	ref := strings.TrimPrefix(head, "ref: ")
+	if ref == head {
+		return head, nil
+	}
+
+	if b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref))); err == nil {
+		return strings.TrimSpace(string(b)), nil
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("unable to read %s\n%w", ref, err)
+	}
+
+	in, err := os.Open(filepath.Join(dir, "packed-refs"))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("unable to open %s\n%w", filepath.Join(dir, "packed-refs"), err)
+	}
+	defer in.Close()
+
+	s := bufio.NewScanner(in)
+	for s.Scan() {
+		if f := strings.Fields(s.Text()); len(f) == 2 && f[1] == ref {
+			return f[0], nil
+		}
+	}
+
+	return "", s.Err()
+}
+
+// ForAgent returns a copy of the provenance for the agent in dependency, or nil if p is nil.
+func (p *Provenance) ForAgent(dependency libpak.BuildpackDependency) *Provenance {
+	if p == nil {
+		return nil
+	}
+
+	q := *p
+	q.AgentVersion = dependency.Version
+	return &q
+}
+
+// Properties returns the provenance as properties, keyed by the name of the property without the
+// helper.ProvenancePrefix.
+func (p Provenance) Properties() map[string]string {
+	properties := map[string]string{
+		"buildpack.version": p.BuildpackVersion,
+		"timestamp":         p.Timestamp.Format(time.RFC3339),
+	}
+
+	if p.AgentVersion != "" {
+		properties["agent.version"] = p.AgentVersion
+	}
+
+	if p.Revision != "" {
+		properties["revision"] = p.Revision
+	}
+
+	return properties
+}
+
+// ProvenanceLayer contributes the provenance to a launch layer of its own and configures the launch helper to read it.
+// As the timestamp changes with every build, keeping it out of the agent layer means that the cached agent layer is
+// not rewritten by every build.
+type ProvenanceLayer struct {
+	LayerContributor libpak.LayerContributor
+	Logger           bard.Logger
+	Provenance       Provenance
+}
+
+func NewProvenanceLayer(provenance Provenance) ProvenanceLayer {
+	return ProvenanceLayer{
+		LayerContributor: libpak.NewLayerContributor(
+			"AppDynamics Build Provenance",
+			map[string]interface{}{"provenance": provenance.Properties()},
+			libcnb.LayerTypes{Launch: true},
+		),
+		Provenance: provenance,
+	}
+}
+
+func (p ProvenanceLayer) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	p.LayerContributor.Logger = p.Logger
+
+	return p.LayerContributor.Contribute(layer, func() (libcnb.Layer, error) {
+		properties := p.Provenance.Properties()
+
+		var keys []string
+		for k := range properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var b strings.Builder
+		for _, k := range keys {
+			_, _ = fmt.Fprintf(&b, "%s=%s\n", k, properties[k])
+		}
+
+		file := filepath.Join(layer.Path, helper.ProvenanceFile)
+		if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to write %s\n%w", file, err)
+		}
+
+		layer.LaunchEnvironment.Default("BPI_APPD_PROVENANCE", file)
+
+		return layer, nil
+	})
+}
+
+func (ProvenanceLayer) Name() string {
+	return "appdynamics-provenance"
+}
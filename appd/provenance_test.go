@@ -0,0 +1,148 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/appd"
+)
+
+func testProvenance(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		ctx libcnb.BuildContext
+	)
+
+	it.Before(func() {
+		ctx.Application.Path = t.TempDir()
+		ctx.Buildpack.Info.Version = "test-buildpack-version"
+	})
+
+	context("GitRevision", func() {
+		var git string
+
+		it.Before(func() {
+			git = filepath.Join(ctx.Application.Path, ".git")
+			Expect(os.MkdirAll(filepath.Join(git, "refs", "heads"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(git, "HEAD"), []byte("ref: refs/heads/main\n"), 0644)).To(Succeed())
+		})
+
+		it("returns empty revision without .git", func() {
+			Expect(appd.GitRevision(t.TempDir())).To(BeEmpty())
+		})
+
+		it("reads branch revision", func() {
+			Expect(os.WriteFile(filepath.Join(git, "refs", "heads", "main"), []byte("test-revision\n"), 0644)).To(Succeed())
+
+			Expect(appd.GitRevision(ctx.Application.Path)).To(Equal("test-revision"))
+		})
+
+		it("reads packed branch revision", func() {
+			Expect(os.WriteFile(filepath.Join(git, "packed-refs"), []byte(`# pack-refs with: peeled fully-peeled sorted
+test-other-revision refs/heads/other
+test-revision refs/heads/main
+`), 0644)).To(Succeed())
+
+			Expect(appd.GitRevision(ctx.Application.Path)).To(Equal("test-revision"))
+		})
+
+		it("reads detached revision", func() {
+			Expect(os.WriteFile(filepath.Join(git, "HEAD"), []byte("test-revision\n"), 0644)).To(Succeed())
+
+			Expect(appd.GitRevision(ctx.Application.Path)).To(Equal("test-revision"))
+		})
+	})
+
+	it("uses $BP_APPD_BUILD_REVISION and $SOURCE_DATE_EPOCH", func() {
+		t.Setenv("BP_APPD_BUILD_REVISION", "test-revision")
+		t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+		Expect(appd.NewProvenance(ctx, libpak.ConfigurationResolver{})).To(Equal(appd.Provenance{
+			BuildpackVersion: "test-buildpack-version",
+			Revision:         "test-revision",
+			Timestamp:        time.Unix(1700000000, 0).UTC(),
+		}))
+	})
+
+	context("ProvenanceLayer", func() {
+		var p appd.ProvenanceLayer
+
+		it.Before(func() {
+			ctx.Layers.Path = t.TempDir()
+			p = appd.NewProvenanceLayer(*(&appd.Provenance{
+				BuildpackVersion: "test-buildpack-version",
+				Revision:         "test-revision",
+				Timestamp:        time.Unix(1700000000, 0).UTC(),
+			}).ForAgent(libpak.BuildpackDependency{Version: "test-agent-version"}))
+		})
+
+		it("contributes provenance", func() {
+			layer, err := ctx.Layers.Layer(p.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = p.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(layer.Launch).To(BeTrue())
+			Expect(layer.Cache).To(BeFalse())
+
+			file := filepath.Join(layer.Path, "provenance.properties")
+			Expect(os.ReadFile(file)).To(Equal([]byte(`agent.version=test-agent-version
+buildpack.version=test-buildpack-version
+revision=test-revision
+timestamp=2023-11-14T22:13:20Z
+`)))
+			Expect(layer.LaunchEnvironment).To(Equal(libcnb.Environment{"BPI_APPD_PROVENANCE.default": file}))
+		})
+
+		it("reuses restored layer without a directory", func() {
+			layer, err := ctx.Layers.Layer(p.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = p.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			metadata := layer.Metadata
+
+			Expect(os.RemoveAll(layer.Path)).To(Succeed())
+			Expect(os.WriteFile(layer.Path+".toml", []byte{}, 0644)).To(Succeed())
+
+			layer, err = ctx.Layers.Layer(p.Name())
+			Expect(err).NotTo(HaveOccurred())
+			layer.Metadata = metadata
+
+			_, err = p.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(layer.Path).NotTo(BeAnExistingFile())
+		})
+	})
+
+	it("does not return provenance for nil provenance", func() {
+		var p *appd.Provenance
+
+		Expect(p.ForAgent(libpak.BuildpackDependency{Version: "test-agent-version"})).To(BeNil())
+	})
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sbom"
+)
+
+// SPDXLicenseIDs are the SPDX license identifiers written as a CycloneDX license id. Any other license type is written
+// as a license name, as CycloneDX only accepts ids from the SPDX license list.
+var SPDXLicenseIDs = map[string]bool{
+	"0BSD":         true,
+	"AGPL-3.0":     true,
+	"Apache-1.1":   true,
+	"Apache-2.0":   true,
+	"BSD-2-Clause": true,
+	"BSD-3-Clause": true,
+	"CDDL-1.0":     true,
+	"CDDL-1.1":     true,
+	"EPL-1.0":      true,
+	"EPL-2.0":      true,
+	"GPL-2.0":      true,
+	"GPL-3.0":      true,
+	"ISC":          true,
+	"LGPL-2.1":     true,
+	"LGPL-3.0":     true,
+	"MIT":          true,
+	"MPL-2.0":      true,
+	"PHP-3.01":     true,
+	"Unlicense":    true,
+	"Zlib":         true,
+}
+
+type CycloneDXDocument struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Metadata    CycloneDXMetadata    `json:"metadata"`
+	Components  []CycloneDXComponent `json:"components"`
+}
+
+type CycloneDXMetadata struct {
+	Component  CycloneDXComponent  `json:"component"`
+	Properties []CycloneDXProperty `json:"properties,omitempty"`
+}
+
+type CycloneDXComponent struct {
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Type       string              `json:"type"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	Hashes     []CycloneDXHash     `json:"hashes,omitempty"`
+	Licenses   []CycloneDXLicenses `json:"licenses,omitempty"`
+	CPE        string              `json:"cpe,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Properties []CycloneDXProperty `json:"properties,omitempty"`
+}
+
+type CycloneDXHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+type CycloneDXLicenses struct {
+	License CycloneDXLicense `json:"license"`
+}
+
+type CycloneDXLicense struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NewCycloneDXDocument creates a CycloneDX document describing the dependencies contributed to a layer. No timestamp
+// or serial number is included so that the document is reproducible.
+func NewCycloneDXDocument(layerPath string, dependencies []libpak.BuildpackDependency) CycloneDXDocument {
+	d := CycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.4",
+		Version:     1,
+		Metadata: CycloneDXMetadata{
+			Component: CycloneDXComponent{Type: "file", Name: layerPath},
+		},
+		Components: []CycloneDXComponent{},
+	}
+
+	for _, dep := range dependencies {
+		c := CycloneDXComponent{
+			BOMRef:  dep.PURL,
+			Type:    "library",
+			Name:    dep.Name,
+			Version: dep.Version,
+			PURL:    dep.PURL,
+		}
+
+		if c.BOMRef == "" {
+			c.BOMRef = fmt.Sprintf("%s@%s", dep.ID, dep.Version)
+		}
+
+		if dep.SHA256 != "" {
+			c.Hashes = []CycloneDXHash{{Algorithm: "SHA-256", Content: dep.SHA256}}
+		}
+
+		for i, cpe := range dep.CPEs {
+			if i == 0 {
+				c.CPE = cpe
+			} else {
+				c.Properties = append(c.Properties, CycloneDXProperty{Name: "cpe", Value: cpe})
+			}
+		}
+
+		for _, l := range dep.Licenses {
+			if l := NewCycloneDXLicense(l); l.ID != "" || l.Name != "" {
+				c.Licenses = append(c.Licenses, CycloneDXLicenses{License: l})
+			}
+		}
+
+		d.Components = append(d.Components, c)
+	}
+
+	return d
+}
+
+// NewCycloneDXLicense returns the CycloneDX license for a dependency license. The type is used as the id if it is an
+// SPDX license identifier, otherwise as the name, falling back to the URI if there is no type.
+func NewCycloneDXLicense(license libpak.BuildpackDependencyLicense) CycloneDXLicense {
+	l := CycloneDXLicense{URL: license.URI}
+
+	switch {
+	case SPDXLicenseIDs[license.Type]:
+		l.ID = license.Type
+	case license.Type != "":
+		l.Name = license.Type
+	default:
+		l.Name = license.URI
+	}
+
+	return l
+}
+
+func (c CycloneDXDocument) WriteTo(path string) error {
+	output, err := json.Marshal(&c)
+	if err != nil {
+		return fmt.Errorf("unable to marshal to JSON\n%w", err)
+	}
+
+	if err := os.WriteFile(path, output, 0644); err != nil {
+		return fmt.Errorf("unable to write to path %s\n%w", path, err)
+	}
+
+	return nil
+}
+
+// writeDependencySBOM writes both Syft and CycloneDX SBOMs describing the dependencies contributed to a layer. The
+// properties describe the layer and are only written to the CycloneDX SBOM.
+func writeDependencySBOM(logger bard.Logger, layer libcnb.Layer, dependencies []libpak.BuildpackDependency, properties ...CycloneDXProperty) error {
+	var syftArtifacts []sbom.SyftArtifact
+	for _, dep := range dependencies {
+		syftArtifact, err := dep.AsSyftArtifact()
+		if err != nil {
+			return fmt.Errorf("unable to get Syft Artifact for dependency: %s, \n%w", dep.Name, err)
+		}
+		syftArtifacts = append(syftArtifacts, syftArtifact)
+	}
+
+	sbomPath := layer.SBOMPath(libcnb.SyftJSON)
+	syft := sbom.NewSyftDependency(layer.Path, syftArtifacts)
+	logger.Debugf("Writing Syft SBOM at %s: %+v", sbomPath, syft)
+	if err := syft.WriteTo(sbomPath); err != nil {
+		return fmt.Errorf("unable to write SBOM\n%w", err)
+	}
+
+	return writeCycloneDXSBOM(logger, layer, dependencies, properties...)
+}
+
+// writeCycloneDXSBOM writes a CycloneDX SBOM describing the dependencies contributed to a layer, for layers whose Syft
+// SBOM is written by libpak.DependencyLayerContributor.
+func writeCycloneDXSBOM(logger bard.Logger, layer libcnb.Layer, dependencies []libpak.BuildpackDependency, properties ...CycloneDXProperty) error {
+	sbomPath := layer.SBOMPath(libcnb.CycloneDXJSON)
+	cycloneDX := NewCycloneDXDocument(layer.Path, dependencies)
+	cycloneDX.Metadata.Properties = properties
+	logger.Debugf("Writing CycloneDX SBOM at %s: %+v", sbomPath, cycloneDX)
+	if err := cycloneDX.WriteTo(sbomPath); err != nil {
+		return fmt.Errorf("unable to write SBOM\n%w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/appd"
+)
+
+func testSBOM(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	it("creates CycloneDX document", func() {
+		d := appd.NewCycloneDXDocument("test-path", []libpak.BuildpackDependency{
+			{
+				ID:      "test-id",
+				Name:    "test-name",
+				Version: "1.1.1",
+				CPEs:    []string{"test-cpe-1", "test-cpe-2"},
+				Licenses: []libpak.BuildpackDependencyLicense{
+					{Type: "Apache-2.0", URI: "test-uri"},
+				},
+			},
+		})
+
+		Expect(d.BOMFormat).To(Equal("CycloneDX"))
+		Expect(d.SpecVersion).To(Equal("1.4"))
+		Expect(d.Metadata.Component).To(Equal(appd.CycloneDXComponent{Type: "file", Name: "test-path"}))
+		Expect(d.Components).To(Equal([]appd.CycloneDXComponent{
+			{
+				BOMRef:     "test-id@1.1.1",
+				Type:       "library",
+				Name:       "test-name",
+				Version:    "1.1.1",
+				Licenses:   []appd.CycloneDXLicenses{{License: appd.CycloneDXLicense{ID: "Apache-2.0", URL: "test-uri"}}},
+				CPE:        "test-cpe-1",
+				Properties: []appd.CycloneDXProperty{{Name: "cpe", Value: "test-cpe-2"}},
+			},
+		}))
+	})
+
+	it("writes non-SPDX licenses as names", func() {
+		d := appd.NewCycloneDXDocument("test-path", []libpak.BuildpackDependency{
+			{
+				ID:      "test-id",
+				Version: "1.1.1",
+				Licenses: []libpak.BuildpackDependencyLicense{
+					{Type: "test-proprietary", URI: "test-uri"},
+					{URI: "test-other-uri"},
+					{},
+				},
+			},
+		})
+
+		Expect(d.Components[0].Licenses).To(Equal([]appd.CycloneDXLicenses{
+			{License: appd.CycloneDXLicense{Name: "test-proprietary", URL: "test-uri"}},
+			{License: appd.CycloneDXLicense{Name: "test-other-uri", URL: "test-other-uri"}},
+		}))
+	})
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak/bindings"
+)
+
+// SignatureVerifier verifies detached signatures of the external configuration with either a cosign public key or a
+// GPG public key.
+type SignatureVerifier struct {
+	CosignKey []byte
+	GPGKey    []byte
+}
+
+// ResolveSignatureVerifier returns a verifier using the cosign.pub or gpg.pub key of a binding of type
+// appdynamics-config-verification. Returns nil if no such binding exists.
+func ResolveSignatureVerifier(binds libcnb.Bindings) (*SignatureVerifier, error) {
+	b, ok, err := bindings.ResolveOne(binds, bindings.OfType("appdynamics-config-verification"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve binding appdynamics-config-verification\n%w", err)
+	} else if !ok {
+		return nil, nil
+	}
+
+	cosign, cosignOk := b.Secret["cosign.pub"]
+	gpg, gpgOk := b.Secret["gpg.pub"]
+
+	switch {
+	case cosignOk && gpgOk:
+		return nil, fmt.Errorf("only one of cosign.pub and gpg.pub may be configured in binding %s", b.Name)
+	case !cosignOk && !gpgOk:
+		return nil, fmt.Errorf("one of cosign.pub or gpg.pub must be configured in binding %s", b.Name)
+	}
+
+	return &SignatureVerifier{CosignKey: []byte(cosign), GPGKey: []byte(gpg)}, nil
+}
+
+// Fingerprint returns the SHA256 of the configured key, identifying it in layer metadata without exposing the key.
+func (s SignatureVerifier) Fingerprint() string {
+	if len(s.GPGKey) > 0 {
+		return fmt.Sprintf("gpg:sha256:%x", sha256.Sum256(s.GPGKey))
+	}
+
+	return fmt.Sprintf("cosign:sha256:%x", sha256.Sum256(s.CosignKey))
+}
+
+// Extension returns the conventional extension of a detached signature for the configured key.
+func (s SignatureVerifier) Extension() string {
+	if len(s.GPGKey) > 0 {
+		return ".asc"
+	}
+
+	return ".sig"
+}
+
+// Verify verifies that signature is a valid detached signature of the content of in.
+func (s SignatureVerifier) Verify(in io.Reader, signature []byte) error {
+	if len(s.GPGKey) > 0 {
+		return s.verifyGPG(in, signature)
+	}
+
+	return s.verifyCosign(in, signature)
+}
+
+func (s SignatureVerifier) verifyCosign(in io.Reader, signature []byte) error {
+	block, _ := pem.Decode(s.CosignKey)
+	if block == nil {
+		return fmt.Errorf("unable to decode cosign public key, no PEM block found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("unable to parse cosign public key\n%w", err)
+	}
+
+	// cosign writes signatures base64 encoded, but raw signatures are accepted as well
+	if b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature))); err == nil {
+		signature = b
+	}
+
+	message, err := io.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("unable to read signed content\n%w", err)
+	}
+	digest := sha256.Sum256(message)
+
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest[:], signature) {
+			return fmt.Errorf("invalid signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("invalid signature\n%w", err)
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, message, signature) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported cosign public key type %T", key)
+	}
+
+	return nil
+}
+
+func (s SignatureVerifier) verifyGPG(in io.Reader, signature []byte) error {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(s.GPGKey))
+	if err != nil {
+		return fmt.Errorf("unable to read GPG public key\n%w", err)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, in, bytes.NewReader(signature), nil)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, in, bytes.NewReader(signature), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid signature\n%w", err)
+	}
+
+	return nil
+}
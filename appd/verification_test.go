@@ -0,0 +1,170 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/appd"
+)
+
+func testVerification(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	context("ResolveSignatureVerifier", func() {
+		it("returns nil without binding", func() {
+			Expect(appd.ResolveSignatureVerifier(nil)).To(BeNil())
+		})
+
+		it("resolves cosign key", func() {
+			Expect(appd.ResolveSignatureVerifier(libcnb.Bindings{
+				{Name: "test-binding", Type: "appdynamics-config-verification", Secret: map[string]string{"cosign.pub": "test-key"}},
+			})).To(Equal(&appd.SignatureVerifier{CosignKey: []byte("test-key"), GPGKey: []byte{}}))
+		})
+
+		it("fails with both keys", func() {
+			_, err := appd.ResolveSignatureVerifier(libcnb.Bindings{
+				{Name: "test-binding", Type: "appdynamics-config-verification", Secret: map[string]string{"cosign.pub": "test-key", "gpg.pub": "test-key"}},
+			})
+			Expect(err).To(MatchError("only one of cosign.pub and gpg.pub may be configured in binding test-binding"))
+		})
+
+		it("fails without keys", func() {
+			_, err := appd.ResolveSignatureVerifier(libcnb.Bindings{
+				{Name: "test-binding", Type: "appdynamics-config-verification", Secret: map[string]string{}},
+			})
+			Expect(err).To(MatchError("one of cosign.pub or gpg.pub must be configured in binding test-binding"))
+		})
+	})
+
+	it("fingerprints the key", func() {
+		Expect(appd.SignatureVerifier{CosignKey: []byte("test-key")}.Fingerprint()).
+			To(Equal("cosign:sha256:62af8704764faf8ea82fc61ce9c4c3908b6cb97d463a634e9e587d7c885db0ef"))
+		Expect(appd.SignatureVerifier{GPGKey: []byte("test-key")}.Fingerprint()).
+			To(Equal("gpg:sha256:62af8704764faf8ea82fc61ce9c4c3908b6cb97d463a634e9e587d7c885db0ef"))
+	})
+
+	context("cosign", func() {
+		var (
+			signature []byte
+			verifier  appd.SignatureVerifier
+		)
+
+		it.Before(func() {
+			var err error
+			signature, verifier.CosignKey, err = cosignSign([]byte("test-content"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("verifies signature", func() {
+			Expect(verifier.Extension()).To(Equal(".sig"))
+			Expect(verifier.Verify(strings.NewReader("test-content"), signature)).To(Succeed())
+		})
+
+		it("fails with modified content", func() {
+			Expect(verifier.Verify(strings.NewReader("other-content"), signature)).To(MatchError("invalid signature"))
+		})
+	})
+
+	context("GPG", func() {
+		var (
+			signature []byte
+			verifier  appd.SignatureVerifier
+		)
+
+		it.Before(func() {
+			var err error
+			signature, verifier.GPGKey, err = gpgSign([]byte("test-content"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("verifies signature", func() {
+			Expect(verifier.Extension()).To(Equal(".asc"))
+			Expect(verifier.Verify(strings.NewReader("test-content"), signature)).To(Succeed())
+		})
+
+		it("fails with modified content", func() {
+			Expect(verifier.Verify(strings.NewReader("other-content"), signature)).To(MatchError(ContainSubstring("invalid signature")))
+		})
+	})
+}
+
+// cosignSign returns a base64 encoded signature of content and the PEM encoded public key that verifies it.
+func cosignSign(content []byte) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	digest := sha256.Sum256(content)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(signature)),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}), nil
+}
+
+// gpgSign returns an armored detached signature of content and the armored public key that verifies it.
+func gpgSign(content []byte) ([]byte, []byte, error) {
+	entity, err := openpgp.NewEntity("test-name", "", "test@localhost", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signature := bytes.NewBuffer(nil)
+	if err := openpgp.ArmoredDetachSign(signature, entity, bytes.NewReader(content), nil); err != nil {
+		return nil, nil, err
+	}
+
+	public := bytes.NewBuffer(nil)
+	w, err := armor.Encode(public, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := entity.Serialize(w); err != nil {
+		return nil, nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	return signature.Bytes(), public.Bytes(), nil
+}
@@ -19,20 +19,122 @@ package appd
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/sherpa"
 )
 
-func VersionDirectory(layer libcnb.Layer) (string, error) {
+// VersionDirectory returns the agent's ver* directory. If $BP_APPD_JAVA_VERSION_DIR is set, that directory is used.
+// Otherwise, the directory with the highest version that contains a conf directory is chosen.
+func VersionDirectory(layer libcnb.Layer, configurationResolver libpak.ConfigurationResolver) (string, error) {
 	file := filepath.Join(layer.Path, "ver*")
-	v, err := filepath.Glob(file)
+	candidates, err := filepath.Glob(file)
 	if err != nil {
 		return "", fmt.Errorf("unable to glob %s\n%w", file, err)
 	}
 
-	if len(v) != 1 {
-		return "", fmt.Errorf("unable to determin version directory from candidates %s", v)
+	if s, ok := configurationResolver.Resolve("BP_APPD_JAVA_VERSION_DIR"); ok {
+		file = filepath.Join(layer.Path, s)
+		if ok, err := sherpa.DirExists(file); err != nil {
+			return "", fmt.Errorf("unable to check %s\n%w", file, err)
+		} else if !ok {
+			return "", fmt.Errorf("version directory %s from $BP_APPD_JAVA_VERSION_DIR does not exist, candidates are %s",
+				s, names(candidates))
+		}
+
+		return file, nil
+	}
+
+	var (
+		best        string
+		bestVersion []int
+		ambiguous   bool
+	)
+
+	for _, c := range candidates {
+		if ok, err := sherpa.DirExists(filepath.Join(c, "conf")); err != nil {
+			return "", fmt.Errorf("unable to check %s\n%w", c, err)
+		} else if !ok {
+			continue
+		}
+
+		v, ok := parseVersion(strings.TrimPrefix(filepath.Base(c), "ver"))
+		if !ok {
+			if best != "" {
+				ambiguous = true
+			}
+			best, bestVersion = c, nil
+			continue
+		}
+
+		switch r := compareVersions(v, bestVersion); {
+		case best == "" || r > 0:
+			best, bestVersion, ambiguous = c, v, false
+		case r == 0:
+			ambiguous = true
+		}
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("unable to determine version directory, no candidate in %s contains a conf directory", names(candidates))
+	}
+
+	if ambiguous {
+		return "", fmt.Errorf("unable to determine version directory from candidates %s, set $BP_APPD_JAVA_VERSION_DIR to choose one",
+			names(candidates))
+	}
+
+	return best, nil
+}
+
+// parseVersion parses a dot-separated version with numeric components.
+func parseVersion(s string) ([]int, bool) {
+	var v []int
+	for _, p := range strings.Split(s, ".") {
+		i, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, false
+		}
+		v = append(v, i)
+	}
+
+	return v, true
+}
+
+// compareVersions compares two parsed versions, treating missing components as zero. A nil version, which could not
+// be parsed, is neither greater nor less than any other version.
+func compareVersions(a []int, b []int) int {
+	if a == nil || b == nil {
+		return 0
 	}
 
-	return v[0], nil
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+
+	return 0
+}
+
+func names(paths []string) []string {
+	n := make([]string, 0, len(paths))
+	for _, p := range paths {
+		n = append(n, filepath.Base(p))
+	}
+	return n
 }
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/appd"
+)
+
+func testVersionDirectory(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layer libcnb.Layer
+	)
+
+	it.Before(func() {
+		var err error
+
+		layer.Path, err = ioutil.TempDir("", "version-directory")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(layer.Path)).To(Succeed())
+	})
+
+	it("returns single version directory", func() {
+		Expect(os.MkdirAll(filepath.Join(layer.Path, "ver4.5.7.25056", "conf"), 0755)).To(Succeed())
+
+		Expect(appd.VersionDirectory(layer, libpak.ConfigurationResolver{})).To(Equal(filepath.Join(layer.Path, "ver4.5.7.25056")))
+	})
+
+	it("returns highest version directory containing conf", func() {
+		Expect(os.MkdirAll(filepath.Join(layer.Path, "ver4.5.10.1", "conf"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(layer.Path, "ver4.5.7.25056", "conf"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(layer.Path, "ver5.0.0.1"), 0755)).To(Succeed())
+
+		Expect(appd.VersionDirectory(layer, libpak.ConfigurationResolver{})).To(Equal(filepath.Join(layer.Path, "ver4.5.10.1")))
+	})
+
+	it("fails when no version directory contains conf", func() {
+		Expect(os.MkdirAll(filepath.Join(layer.Path, "ver4.5.7.25056"), 0755)).To(Succeed())
+
+		_, err := appd.VersionDirectory(layer, libpak.ConfigurationResolver{})
+		Expect(err).To(MatchError("unable to determine version directory, no candidate in [ver4.5.7.25056] contains a conf directory"))
+	})
+
+	it("fails when versions are ambiguous", func() {
+		Expect(os.MkdirAll(filepath.Join(layer.Path, "ver4.5.7", "conf"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(layer.Path, "ver4.5.7.0", "conf"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(layer.Path, "ver-custom", "conf"), 0755)).To(Succeed())
+
+		_, err := appd.VersionDirectory(layer, libpak.ConfigurationResolver{})
+		Expect(err).To(MatchError("unable to determine version directory from candidates [ver-custom ver4.5.7 ver4.5.7.0], set $BP_APPD_JAVA_VERSION_DIR to choose one"))
+	})
+
+	context("$BP_APPD_JAVA_VERSION_DIR", func() {
+		it.Before(func() {
+			t.Setenv("BP_APPD_JAVA_VERSION_DIR", "ver4.5.7.25056")
+		})
+
+		it("returns configured version directory", func() {
+			Expect(os.MkdirAll(filepath.Join(layer.Path, "ver4.5.7.25056"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(layer.Path, "ver5.0.0.1", "conf"), 0755)).To(Succeed())
+
+			Expect(appd.VersionDirectory(layer, libpak.ConfigurationResolver{})).To(Equal(filepath.Join(layer.Path, "ver4.5.7.25056")))
+		})
+
+		it("fails when configured version directory does not exist", func() {
+			Expect(os.MkdirAll(filepath.Join(layer.Path, "ver5.0.0.1", "conf"), 0755)).To(Succeed())
+
+			_, err := appd.VersionDirectory(layer, libpak.ConfigurationResolver{})
+			Expect(err).To(MatchError("version directory ver4.5.7.25056 from $BP_APPD_JAVA_VERSION_DIR does not exist, candidates are [ver5.0.0.1]"))
+		})
+	})
+}
@@ -19,6 +19,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/paketo-buildpacks/libpak/bard"
@@ -31,16 +32,64 @@ func main() {
 	sherpa.Execute(func() error {
 		var (
 			err error
-			p   = helper.Properties{Logger: bard.NewLogger(os.Stdout)}
+			l   = bard.NewLogger(os.Stdout)
+			p   = helper.Properties{Logger: l}
+			f   = helper.Preflight{Logger: l}
+			o   = helper.OpenTelemetry{Logger: l}
+			v   = helper.Provenance{Logger: l}
+			u   = helper.UniqueHostID{CgroupPath: "/proc/self/cgroup", Logger: l, MountInfoPath: "/proc/self/mountinfo"}
 		)
 
 		p.Bindings, err = libcnb.NewBindingsFromEnvironment()
 		if err != nil {
 			return fmt.Errorf("unable to read bindings from environment\n%w", err)
 		}
+		f.Bindings = p.Bindings
+		o.Bindings = p.Bindings
+		v.Bindings = p.Bindings
+		u.Bindings = p.Bindings
 
+		if len(os.Args) > 1 && os.Args[1] == "appd-diagnose" {
+			// a command run with kubectl exec has neither the launch environment nor the values exported by the exec.d
+			// helpers, so the environment of the application process is diagnosed instead
+			source := "/proc/1/environ"
+			if environ, err := helper.ProcessEnvironment(source); err != nil {
+				source = fmt.Sprintf("helper process, %s", strings.ReplaceAll(err.Error(), "\n", ": "))
+			} else {
+				for _, kv := range environ {
+					k, v, _ := strings.Cut(kv, "=")
+					if err := os.Setenv(k, v); err != nil {
+						return fmt.Errorf("unable to set $%s\n%w", k, err)
+					}
+				}
+			}
+
+			b, err := libcnb.NewBindingsFromEnvironment()
+			if err != nil {
+				return fmt.Errorf("unable to read bindings from environment\n%w", err)
+			}
+
+			exe, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("unable to determine executable path\n%w", err)
+			}
+
+			d := helper.Diagnose{
+				AgentLayerPath:    helper.AgentPath(exe),
+				Bindings:          b,
+				EnvironmentSource: source,
+				Preflight:         helper.Preflight{Bindings: b},
+			}
+			return d.Run(os.Stdout, os.Args[2:])
+		}
+
+		// helpers run in lexical order, so a unique host ID from the binding is exported before unique-host-id runs
 		return sherpa.Helpers(map[string]sherpa.ExecD{
-			"properties": p,
+			"opentelemetry":  o,
+			"properties":     p,
+			"preflight":      f,
+			"provenance":     v,
+			"unique-host-id": u,
 		})
 	})
 }
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak/bindings"
+)
+
+// ResolveBinding returns the binding of type AppDynamics. If the environment variable named variable is set, the
+// binding with that name is returned, otherwise exactly one binding of type AppDynamics must exist.
+func ResolveBinding(binds libcnb.Bindings, variable string) (libcnb.Binding, bool, error) {
+	resolved := bindings.Resolve(binds, bindings.OfType("AppDynamics"))
+
+	var names []string
+	for _, b := range resolved {
+		names = append(names, b.Name)
+	}
+	sort.Strings(names)
+
+	if name, ok := os.LookupEnv(variable); ok && name != "" {
+		for _, b := range resolved {
+			if b.Name == name {
+				return b, true, nil
+			}
+		}
+
+		return libcnb.Binding{}, false, fmt.Errorf("no binding of type AppDynamics named %s from $%s, available bindings are %s",
+			name, variable, strings.Join(names, ", "))
+	}
+
+	switch len(resolved) {
+	case 0:
+		return libcnb.Binding{}, false, nil
+	case 1:
+		return resolved[0], true, nil
+	default:
+		return libcnb.Binding{}, false, fmt.Errorf("multiple bindings of type AppDynamics found, set $%s to one of %s",
+			variable, strings.Join(names, ", "))
+	}
+}
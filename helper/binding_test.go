@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper_test
+
+import (
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/helper"
+)
+
+func testBinding(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		binds = libcnb.Bindings{
+			{Name: "test-prod", Type: "AppDynamics"},
+			{Name: "test-dr", Type: "appdynamics"},
+			{Name: "test-other", Type: "test-type"},
+		}
+	)
+
+	it("returns false without binding", func() {
+		_, ok, err := helper.ResolveBinding(libcnb.Bindings{}, "TEST_BINDING_NAME")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	it("returns single binding", func() {
+		b, ok, err := helper.ResolveBinding(binds[:1], "TEST_BINDING_NAME")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(b.Name).To(Equal("test-prod"))
+	})
+
+	it("fails with multiple bindings", func() {
+		_, _, err := helper.ResolveBinding(binds, "TEST_BINDING_NAME")
+		Expect(err).To(MatchError("multiple bindings of type AppDynamics found, set $TEST_BINDING_NAME to one of test-dr, test-prod"))
+	})
+
+	it("selects binding by name", func() {
+		t.Setenv("TEST_BINDING_NAME", "test-dr")
+
+		b, ok, err := helper.ResolveBinding(binds, "TEST_BINDING_NAME")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(b.Name).To(Equal("test-dr"))
+	})
+
+	it("fails with unknown name", func() {
+		t.Setenv("TEST_BINDING_NAME", "test-other")
+
+		_, _, err := helper.ResolveBinding(binds, "TEST_BINDING_NAME")
+		Expect(err).To(MatchError("no binding of type AppDynamics named test-other from $TEST_BINDING_NAME, available bindings are test-dr, test-prod"))
+	})
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+// DeactivateMarker is set at build time when the agent is contributed without a binding because $BP_APPD_ENABLED is
+// set. Only images carrying it deactivate the agent at launch.
+const DeactivateMarker = "BPI_APPD_DEACTIVATE_UNCONFIGURED"
+
+// Deactivated returns whether the agent contributed without a binding should be deactivated at launch because neither
+// a binding, $APPDYNAMICS_CONTROLLER_HOST_NAME, nor -Dappdynamics.controller.hostName configures a controller.
+func Deactivated(bindings libcnb.Bindings) (bool, error) {
+	if !sherpa.ResolveBool(DeactivateMarker) {
+		return false, nil
+	}
+
+	if _, ok, err := ResolveBinding(bindings, "BPL_APPD_BINDING_NAME"); err != nil {
+		return false, fmt.Errorf("unable to resolve binding AppDynamics\n%w", err)
+	} else if ok {
+		return false, nil
+	}
+
+	if _, ok := os.LookupEnv("APPDYNAMICS_CONTROLLER_HOST_NAME"); ok {
+		return false, nil
+	}
+
+	return !JavaOptionSet("appdynamics.controller.hostName"), nil
+}
+
+// Deactivate returns the environment that removes the Java and PHP agents contributed by the buildpack from
+// $JAVA_TOOL_OPTIONS and $PHP_INI_SCAN_DIR.
+func Deactivate() map[string]string {
+	e := map[string]string{}
+
+	if s, ok := os.LookupEnv("JAVA_TOOL_OPTIONS"); ok {
+		options, _ := split(s, unicode.IsSpace, false)
+
+		var kept []string
+		for _, o := range options {
+			if javaAgentOption(o.Value) || strings.HasPrefix(o.Value, "-D"+JavaPropertyPrefix) {
+				continue
+			}
+			kept = append(kept, o.Raw)
+		}
+
+		if len(kept) != len(options) {
+			e["JAVA_TOOL_OPTIONS"] = strings.Join(kept, " ")
+		}
+	}
+
+	if s, ok := os.LookupEnv("PHP_INI_SCAN_DIR"); ok {
+		dirs := strings.Split(s, string(os.PathListSeparator))
+
+		var kept []string
+		for _, d := range dirs {
+			if strings.Contains(d, string(filepath.Separator)+"appdynamics-php"+string(filepath.Separator)) {
+				continue
+			}
+			kept = append(kept, d)
+		}
+
+		if len(kept) != len(dirs) {
+			e["PHP_INI_SCAN_DIR"] = strings.Join(kept, string(os.PathListSeparator))
+		}
+	}
+
+	return e
+}
+
+// JavaAgentConfigured returns whether $JAVA_TOOL_OPTIONS contains the Java agent contributed by the buildpack.
+func JavaAgentConfigured() bool {
+	for _, o := range JavaToolOptions() {
+		if javaAgentOption(o) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func javaAgentOption(o string) bool {
+	return strings.HasPrefix(o, "-javaagent:") &&
+		strings.Contains(o, string(filepath.Separator)+"appdynamics-java"+string(filepath.Separator))
+}
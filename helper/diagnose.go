@@ -0,0 +1,240 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/buildpacks/libcnb"
+)
+
+// Diagnose reports the state of the AppDynamics integration in a running container. It is run as
+// `<layers>/paketo-buildpacks_appdynamics/helper/helper appd-diagnose` rather than as an exec.d helper, and reads the
+// environment of the helper process, which should first be set to the environment of the application with
+// ProcessEnvironment.
+type Diagnose struct {
+	AgentLayerPath    string
+	Bindings          libcnb.Bindings
+	EnvironmentSource string
+	Preflight         Preflight
+}
+
+// ProcessEnvironment returns the environment of a process from its environ file, e.g. /proc/1/environ. Unlike the
+// environment of a command run with `kubectl exec`, the environment of the application process includes the launch
+// environment of the layers and the values exported by the exec.d helpers.
+func ProcessEnvironment(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	var environ []string
+	for _, kv := range strings.Split(string(b), "\x00") {
+		if kv != "" {
+			environ = append(environ, kv)
+		}
+	}
+
+	return environ, nil
+}
+
+// AgentPath returns the path of the agent layer recorded at build time in $BPI_APPD_AGENT_PATH. If it is not set, the
+// Java or PHP agent layer is found alongside the helper layer, as the helper is installed at
+// <layers>/<buildpack-id>/helper/helper.
+func AgentPath(executable string) string {
+	if s, ok := os.LookupEnv("BPI_APPD_AGENT_PATH"); ok && s != "" {
+		return s
+	}
+
+	layers := filepath.Dir(filepath.Dir(executable))
+	for _, n := range []string{"appdynamics-java", "appdynamics-php"} {
+		if s, err := os.Stat(filepath.Join(layers, n)); err == nil && s.IsDir() {
+			return filepath.Join(layers, n)
+		}
+	}
+
+	return filepath.Join(layers, "appdynamics-java")
+}
+
+// Diagnosis is the result of Diagnose.
+type Diagnosis struct {
+	EnvironmentSource    string    `json:"environmentSource"`
+	Binding              string    `json:"binding"`
+	BindingError         string    `json:"bindingError,omitempty"`
+	Environment          []Setting `json:"environment"`
+	AgentLayer           string    `json:"agentLayer"`
+	AgentVersion         string    `json:"agentVersion,omitempty"`
+	JavaAgentJar         bool      `json:"javaAgentJar"`
+	JavaAgentConfigured  bool      `json:"javaAgentConfigured"`
+	PHPAgent             bool      `json:"phpAgent"`
+	PHPAgentConfigured   bool      `json:"phpAgentConfigured"`
+	LogDirectory         string    `json:"logDirectory,omitempty"`
+	LogDirectoryWritable bool      `json:"logDirectoryWritable"`
+	Controller           string    `json:"controller"`
+	ControllerReachable  bool      `json:"controllerReachable"`
+	ControllerError      string    `json:"controllerError,omitempty"`
+}
+
+// Run parses the command line arguments and writes the diagnosis to w.
+func (d Diagnose) Run(w io.Writer, arguments []string) error {
+	f := flag.NewFlagSet("appd-diagnose", flag.ContinueOnError)
+	f.SetOutput(w)
+	format := f.String("format", "text", "output format, one of text or json")
+	timeout := f.Duration("timeout", 5*time.Second, "timeout of the controller reachability check")
+
+	if err := f.Parse(arguments); err != nil {
+		return err
+	}
+
+	diagnosis := d.Diagnose(*timeout)
+
+	switch *format {
+	case "json":
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(diagnosis)
+	case "text":
+		return diagnosis.WriteText(w)
+	default:
+		return fmt.Errorf("unsupported format %s, must be one of text or json", *format)
+	}
+}
+
+// Diagnose collects the diagnosis. Failures are recorded in the diagnosis rather than returned.
+func (d Diagnose) Diagnose(timeout time.Duration) Diagnosis {
+	diagnosis := Diagnosis{AgentLayer: d.AgentLayerPath, EnvironmentSource: d.EnvironmentSource}
+
+	b, ok, err := ResolveBinding(d.Bindings, "BPL_APPD_BINDING_NAME")
+	if err != nil {
+		diagnosis.BindingError = err.Error()
+	} else if ok {
+		diagnosis.Binding = b.Name
+	}
+
+	diagnosis.Environment = EffectiveSettings(Environment(os.Environ()), b)
+
+	diagnosis.JavaAgentJar = fileExists(filepath.Join(d.AgentLayerPath, "javaagent.jar"))
+	diagnosis.JavaAgentConfigured = strings.Contains(os.Getenv("JAVA_TOOL_OPTIONS"), "-javaagent:")
+
+	if filepath.Base(d.AgentLayerPath) == "appdynamics-php" {
+		diagnosis.PHPAgent = true
+		diagnosis.PHPAgentConfigured = strings.Contains(os.Getenv("PHP_INI_SCAN_DIR"), filepath.Join(d.AgentLayerPath, "php.ini.d"))
+		diagnosis.LogDirectory = filepath.Join(d.AgentLayerPath, "logs")
+		diagnosis.LogDirectoryWritable = writable(diagnosis.LogDirectory)
+	} else if v := versionDirectories(d.AgentLayerPath); len(v) > 0 {
+		dir := v[len(v)-1]
+		diagnosis.AgentVersion = strings.TrimPrefix(filepath.Base(dir), "ver")
+		diagnosis.LogDirectory = filepath.Join(dir, "logs")
+		diagnosis.LogDirectoryWritable = writable(diagnosis.LogDirectory)
+	}
+
+	diagnosis.Controller, err = d.Preflight.Check(b, timeout)
+	if err != nil {
+		diagnosis.ControllerError = strings.ReplaceAll(err.Error(), "\n", ": ")
+	} else {
+		diagnosis.ControllerReachable = true
+	}
+
+	return diagnosis
+}
+
+// WriteText writes the diagnosis in a human readable form.
+func (d Diagnosis) WriteText(w io.Writer) error {
+	binding := d.Binding
+	if d.BindingError != "" {
+		binding = fmt.Sprintf("error: %s", d.BindingError)
+	} else if binding == "" {
+		binding = "none"
+	}
+
+	controller := "reachable"
+	if !d.ControllerReachable {
+		controller = fmt.Sprintf("unreachable: %s", d.ControllerError)
+	}
+
+	lines := [][2]string{
+		{"Environment source", d.EnvironmentSource},
+		{"Binding", binding},
+		{"Agent layer", d.AgentLayer},
+	}
+	if d.PHPAgent {
+		lines = append(lines, [2]string{"php.ini.d in $PHP_INI_SCAN_DIR", fmt.Sprint(d.PHPAgentConfigured)})
+	} else {
+		lines = append(lines,
+			[2]string{"Agent version", d.AgentVersion},
+			[2]string{"javaagent.jar present", fmt.Sprint(d.JavaAgentJar)},
+			[2]string{"-javaagent in $JAVA_TOOL_OPTIONS", fmt.Sprint(d.JavaAgentConfigured)},
+		)
+	}
+	lines = append(lines,
+		[2]string{"Log directory", d.LogDirectory},
+		[2]string{"Log directory writable", fmt.Sprint(d.LogDirectoryWritable)},
+		[2]string{"Controller", fmt.Sprintf("%s %s", d.Controller, controller)},
+	)
+
+	for _, l := range lines {
+		if _, err := fmt.Fprintf(w, "%s: %s\n", l[0], l[1]); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintln(w, "Environment:"); err != nil {
+		return err
+	}
+
+	return WriteSettings(w, d.Environment)
+}
+
+func fileExists(path string) bool {
+	s, err := os.Stat(path)
+	return err == nil && !s.IsDir()
+}
+
+func versionDirectories(layer string) []string {
+	candidates, err := filepath.Glob(filepath.Join(layer, "ver*"))
+	if err != nil {
+		return nil
+	}
+
+	var dirs []string
+	for _, c := range candidates {
+		if s, err := os.Stat(c); err == nil && s.IsDir() {
+			dirs = append(dirs, c)
+		}
+	}
+	sort.Strings(dirs)
+
+	return dirs
+}
+
+func writable(dir string) bool {
+	f, err := os.CreateTemp(dir, ".appd-diagnose-")
+	if err != nil {
+		return false
+	}
+	f.Close()
+
+	return os.Remove(f.Name()) == nil
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/helper"
+)
+
+func testDiagnose(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		buffer   *bytes.Buffer
+		d        helper.Diagnose
+		listener net.Listener
+	)
+
+	it.Before(func() {
+		var err error
+
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		host, port, err := net.SplitHostPort(listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+
+		buffer = bytes.NewBuffer(nil)
+		d = helper.Diagnose{
+			AgentLayerPath:    t.TempDir(),
+			EnvironmentSource: "/proc/1/environ",
+			Bindings: libcnb.Bindings{
+				{
+					Name: "test-binding",
+					Type: "AppDynamics",
+					Secret: map[string]string{
+						"agent-account-access-key": "test-access-key",
+						"controller-host-name":     host,
+						"controller-port":          port,
+					},
+				},
+			},
+		}
+
+		Expect(os.MkdirAll(filepath.Join(d.AgentLayerPath, "ver1.1.1.1", "logs"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(d.AgentLayerPath, "javaagent.jar"), []byte{}, 0644)).To(Succeed())
+		t.Setenv("APPDYNAMICS_AGENT_ACCOUNT_ACCESS_KEY", "test-access-key")
+		t.Setenv("JAVA_TOOL_OPTIONS", "-javaagent:test-path")
+	})
+
+	it.After(func() {
+		listener.Close()
+	})
+
+	it("writes JSON", func() {
+		Expect(d.Run(buffer, []string{"-format", "json"})).To(Succeed())
+
+		var diagnosis helper.Diagnosis
+		Expect(json.Unmarshal(buffer.Bytes(), &diagnosis)).To(Succeed())
+		Expect(diagnosis.EnvironmentSource).To(Equal("/proc/1/environ"))
+		Expect(diagnosis.Binding).To(Equal("test-binding"))
+		Expect(diagnosis.AgentVersion).To(Equal("1.1.1.1"))
+		Expect(diagnosis.JavaAgentJar).To(BeTrue())
+		Expect(diagnosis.JavaAgentConfigured).To(BeTrue())
+		Expect(diagnosis.LogDirectory).To(Equal(filepath.Join(d.AgentLayerPath, "ver1.1.1.1", "logs")))
+		Expect(diagnosis.LogDirectoryWritable).To(BeTrue())
+		Expect(diagnosis.Controller).To(Equal(listener.Addr().String()))
+		Expect(diagnosis.ControllerReachable).To(BeTrue())
+		Expect(diagnosis.Environment).To(ContainElement(helper.Setting{
+			Name: "APPDYNAMICS_AGENT_ACCOUNT_ACCESS_KEY", Source: "binding", Value: "********",
+		}))
+		Expect(buffer.String()).NotTo(ContainSubstring("test-access-key"))
+	})
+
+	it("writes text", func() {
+		Expect(d.Run(buffer, []string{})).To(Succeed())
+
+		Expect(buffer.String()).To(ContainSubstring("Environment source: /proc/1/environ\n"))
+		Expect(buffer.String()).To(ContainSubstring("Binding: test-binding\n"))
+		Expect(buffer.String()).To(ContainSubstring("Agent version: 1.1.1.1\n"))
+		Expect(buffer.String()).To(ContainSubstring("-javaagent in $JAVA_TOOL_OPTIONS: true\n"))
+		Expect(buffer.String()).To(ContainSubstring("reachable\n"))
+		Expect(buffer.String()).To(MatchRegexp(`APPDYNAMICS_AGENT_ACCOUNT_ACCESS_KEY\s+binding\s+\*{8}`))
+	})
+
+	it("reports the environment of the process rather than the binding", func() {
+		t.Setenv("APPDYNAMICS_AGENT_NODE_NAME", "test-node")
+		d.Bindings[0].Secret["agent-tier-name"] = "test-tier"
+
+		diagnosis := d.Diagnose(time.Second)
+		Expect(diagnosis.Environment).To(ContainElement(helper.Setting{
+			Name: "APPDYNAMICS_AGENT_NODE_NAME", Source: "env", Value: "test-node",
+		}))
+		Expect(diagnosis.Environment).NotTo(ContainElement(HaveField("Name", "APPDYNAMICS_AGENT_TIER_NAME")))
+	})
+
+	it("reads the environment of a process", func() {
+		path := filepath.Join(t.TempDir(), "environ")
+		Expect(os.WriteFile(path, []byte("APPDYNAMICS_AGENT_NODE_NAME=test-node\x00JAVA_TOOL_OPTIONS=-Dtest=a=b\x00"), 0644)).
+			To(Succeed())
+
+		Expect(helper.ProcessEnvironment(path)).To(Equal([]string{
+			"APPDYNAMICS_AGENT_NODE_NAME=test-node",
+			"JAVA_TOOL_OPTIONS=-Dtest=a=b",
+		}))
+
+		_, err := helper.ProcessEnvironment(filepath.Join(t.TempDir(), "does-not-exist"))
+		Expect(err).To(MatchError(ContainSubstring("unable to read")))
+	})
+
+	it("reports unreachable controller", func() {
+		listener.Close()
+
+		diagnosis := d.Diagnose(time.Second)
+		Expect(diagnosis.ControllerReachable).To(BeFalse())
+		Expect(diagnosis.ControllerError).To(ContainSubstring("unable to connect"))
+	})
+
+	it("reports PHP agent", func() {
+		d.AgentLayerPath = filepath.Join(t.TempDir(), "appdynamics-php")
+		Expect(os.MkdirAll(filepath.Join(d.AgentLayerPath, "logs"), 0755)).To(Succeed())
+		t.Setenv("PHP_INI_SCAN_DIR", filepath.Join(d.AgentLayerPath, "php.ini.d"))
+
+		diagnosis := d.Diagnose(time.Second)
+		Expect(diagnosis.PHPAgent).To(BeTrue())
+		Expect(diagnosis.PHPAgentConfigured).To(BeTrue())
+		Expect(diagnosis.LogDirectory).To(Equal(filepath.Join(d.AgentLayerPath, "logs")))
+		Expect(diagnosis.LogDirectoryWritable).To(BeTrue())
+
+		Expect(diagnosis.WriteText(buffer)).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring("php.ini.d in $PHP_INI_SCAN_DIR: true\n"))
+		Expect(buffer.String()).NotTo(ContainSubstring("javaagent.jar"))
+	})
+
+	context("agent path", func() {
+		var layers string
+
+		it.Before(func() {
+			layers = t.TempDir()
+		})
+
+		it("uses $BPI_APPD_AGENT_PATH", func() {
+			t.Setenv("BPI_APPD_AGENT_PATH", "/layers/test/appdynamics-php")
+
+			Expect(helper.AgentPath(filepath.Join(layers, "helper", "helper"))).To(Equal("/layers/test/appdynamics-php"))
+		})
+
+		it("finds the Java agent layer alongside the helper layer", func() {
+			Expect(os.MkdirAll(filepath.Join(layers, "appdynamics-java"), 0755)).To(Succeed())
+
+			Expect(helper.AgentPath(filepath.Join(layers, "helper", "helper"))).To(Equal(filepath.Join(layers, "appdynamics-java")))
+		})
+
+		it("finds the PHP agent layer alongside the helper layer", func() {
+			Expect(os.MkdirAll(filepath.Join(layers, "appdynamics-php"), 0755)).To(Succeed())
+
+			Expect(helper.AgentPath(filepath.Join(layers, "helper", "helper"))).To(Equal(filepath.Join(layers, "appdynamics-php")))
+		})
+	})
+
+	it("fails with unsupported format", func() {
+		Expect(d.Run(buffer, []string{"-format", "test-format"})).
+			To(MatchError("unsupported format test-format, must be one of text or json"))
+	})
+}
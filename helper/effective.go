@@ -0,0 +1,177 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+// SecretMarkers identify variables whose values are masked when the effective configuration is logged.
+var SecretMarkers = []string{"ACCESS_KEY", "PASSWORD", "SECRET", "TOKEN", "APP_KEY"}
+
+// Setting is a single effective APPDYNAMICS_* variable and where its value came from.
+type Setting struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Value  string `json:"value"`
+}
+
+var camelCase = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// Environment returns a map of a list of name=value pairs such as os.Environ().
+func Environment(environ []string) map[string]string {
+	e := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			e[k] = v
+		}
+	}
+
+	return e
+}
+
+// EffectiveSettings returns the APPDYNAMICS_* variables and the -Dappdynamics.* system properties in $JAVA_TOOL_OPTIONS
+// of environment, the final environment of the process, each with its source of binding, env, or default. A value is
+// from the binding if it matches the value the properties helper exports for a key of the binding. Secret values are
+// masked.
+func EffectiveSettings(environment map[string]string, binding libcnb.Binding) []Setting {
+	from := BindingValues(binding)
+
+	var s []Setting
+	for k, v := range environment {
+		if !strings.HasPrefix(k, "APPDYNAMICS_") {
+			continue
+		}
+
+		source := "env"
+		if b, ok := from[k]; ok && b == v {
+			source = "binding"
+		} else if d, ok := environment[DefaultName(k)]; ok && d == v {
+			source = "default"
+		}
+		s = append(s, Setting{Name: k, Source: source, Value: Mask(k, v)})
+	}
+
+	for _, o := range SplitJavaToolOptions(environment["JAVA_TOOL_OPTIONS"]) {
+		if !strings.HasPrefix(o, "-Dappdynamics.") {
+			continue
+		}
+
+		k, v, _ := strings.Cut(o, "=")
+		source := "env"
+		if b, ok := from[k]; ok && b == v {
+			source = "binding"
+		}
+		s = append(s, Setting{Name: k, Source: source, Value: Mask(k, v)})
+	}
+
+	sort.SliceStable(s, func(i, j int) bool { return s[i].Name < s[j].Name })
+	return s
+}
+
+// BindingValues returns the values the properties helper exports for the keys of binding, keyed by the name of the
+// APPDYNAMICS_* variable or the -D system property.
+func BindingValues(binding libcnb.Binding) map[string]string {
+	values := map[string]string{}
+
+	for k, raw := range NormaliseKeys(binding.Secret) {
+		v := binding.Secret[raw]
+
+		if strings.HasPrefix(raw, OpenTelemetryKeyPrefix) {
+			continue
+		}
+
+		if strings.HasPrefix(raw, JavaPropertyKeyPrefix) {
+			if name, err := JavaPropertyName(strings.TrimPrefix(raw, JavaPropertyKeyPrefix)); err == nil {
+				values["-D"+name] = strings.TrimSpace(v)
+			}
+			continue
+		}
+
+		path, _ := binding.SecretFilePath(raw)
+		values[EnvironmentName(k)] = v
+		values[EnvironmentName(strings.TrimSuffix(k, FileSuffix)+FileSuffix)] = path
+
+		if prop, ok := JavaFileProperties[strings.TrimSuffix(k, FileSuffix)]; ok {
+			values["-D"+prop] = path
+		}
+	}
+
+	return values
+}
+
+// Mask returns value, or a fixed mask if name, a variable or system property name, identifies a secret. Paths to
+// secret files are not masked.
+func Mask(name string, value string) string {
+	n := strings.ToUpper(strings.ReplaceAll(camelCase.ReplaceAllString(name, "${1}_${2}"), ".", "_"))
+
+	if strings.HasSuffix(n, "_FILE") {
+		return value
+	}
+
+	for _, m := range SecretMarkers {
+		if strings.Contains(n, m) {
+			return "********"
+		}
+	}
+
+	return value
+}
+
+// LogEffectiveSettings logs the effective settings of environment if $BPL_DEBUG or $BPL_APPD_DEBUG is set.
+func LogEffectiveSettings(logger bard.Logger, environment map[string]string, bindings libcnb.Bindings) error {
+	if !sherpa.ResolveBool("BPL_DEBUG") && !sherpa.ResolveBool("BPL_APPD_DEBUG") {
+		return nil
+	}
+
+	b, _, err := ResolveBinding(bindings, "BPL_APPD_BINDING_NAME")
+	if err != nil {
+		return fmt.Errorf("unable to resolve binding AppDynamics\n%w", err)
+	}
+
+	logger.Info("Effective AppDynamics configuration:")
+	if err := WriteSettings(logger.InfoWriter(), EffectiveSettings(environment, b)); err != nil {
+		return fmt.Errorf("unable to write effective configuration\n%w", err)
+	}
+
+	return nil
+}
+
+// WriteSettings writes the settings as a table.
+func WriteSettings(w io.Writer, settings []Setting) error {
+	t := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if _, err := fmt.Fprintln(t, "NAME\tSOURCE\tVALUE"); err != nil {
+		return err
+	}
+	for _, s := range settings {
+		if _, err := fmt.Fprintf(t, "%s\t%s\t%s\n", s.Name, s.Source, s.Value); err != nil {
+			return err
+		}
+	}
+
+	return t.Flush()
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/helper"
+)
+
+func testEffective(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	it("returns settings with sources", func() {
+		Expect(helper.EffectiveSettings(map[string]string{
+			"APPDYNAMICS_AGENT_ACCOUNT_ACCESS_KEY": "test-access-key",
+			"APPDYNAMICS_AGENT_NODE_NAME":          "test-node",
+			"APPDYNAMICS_AGENT_TIER_NAME":          "test-tier",
+			"APPDYNAMICS_CONTROLLER_KEYSTORE_FILE": "/test/binding/controller-keystore",
+			"BPI_APPD_DEFAULT_AGENT_TIER_NAME":     "test-tier",
+			"JAVA_TOOL_OPTIONS": "-javaagent:/test/javaagent.jar -Dappdynamics.agent.maxMetrics=5000 " +
+				"-Dappdynamics.controller.keystoreFilename=/test/binding/controller-keystore \"-Dappdynamics.http.proxyPassword=test password\"",
+			"PATH": "/usr/bin",
+		}, libcnb.Binding{
+			Name: "test-binding",
+			Type: "AppDynamics",
+			Path: "/test/binding",
+			Secret: map[string]string{
+				"agent-account-access-key": "test-access-key",
+				"agent-node-name":          "test-binding-node",
+				"controller-keystore":      "test-keystore",
+				"jvm.agent.maxMetrics":     "5000",
+			},
+		})).To(Equal([]helper.Setting{
+			{Name: "-Dappdynamics.agent.maxMetrics", Source: "binding", Value: "5000"},
+			{Name: "-Dappdynamics.controller.keystoreFilename", Source: "binding", Value: "/test/binding/controller-keystore"},
+			{Name: "-Dappdynamics.http.proxyPassword", Source: "env", Value: "********"},
+			{Name: "APPDYNAMICS_AGENT_ACCOUNT_ACCESS_KEY", Source: "binding", Value: "********"},
+			{Name: "APPDYNAMICS_AGENT_NODE_NAME", Source: "env", Value: "test-node"},
+			{Name: "APPDYNAMICS_AGENT_TIER_NAME", Source: "default", Value: "test-tier"},
+			{Name: "APPDYNAMICS_CONTROLLER_KEYSTORE_FILE", Source: "binding", Value: "/test/binding/controller-keystore"},
+		}))
+	})
+
+	it("masks secrets", func() {
+		Expect(helper.Mask("APPDYNAMICS_AGENT_ACCOUNT_ACCESS_KEY", "test-value")).To(Equal("********"))
+		Expect(helper.Mask("APPDYNAMICS_TEST_PASSWORD", "test-value")).To(Equal("********"))
+		Expect(helper.Mask("APPDYNAMICS_AGENT_ACCOUNT_ACCESS_KEY_FILE", "test-value")).To(Equal("test-value"))
+		Expect(helper.Mask("APPDYNAMICS_AGENT_NODE_NAME", "test-value")).To(Equal("test-value"))
+		Expect(helper.Mask("-Dappdynamics.agent.accountAccessKey", "test-value")).To(Equal("********"))
+		Expect(helper.Mask("-Dappdynamics.controller.keystorePassword", "test-value")).To(Equal("********"))
+		Expect(helper.Mask("-Dappdynamics.controller.keystoreFilename", "test-value")).To(Equal("test-value"))
+	})
+
+	it("writes table", func() {
+		buffer := bytes.NewBuffer(nil)
+
+		Expect(helper.WriteSettings(buffer, []helper.Setting{
+			{Name: "APPDYNAMICS_AGENT_NODE_NAME", Source: "env", Value: "test-node"},
+		})).To(Succeed())
+		Expect(buffer.String()).To(Equal("NAME                         SOURCE  VALUE\nAPPDYNAMICS_AGENT_NODE_NAME  env     test-node\n"))
+	})
+}
@@ -25,6 +25,13 @@ import (
 
 func TestUnit(t *testing.T) {
 	suite := spec.New("helper", spec.Report(report.Terminal{}))
+	suite("Binding", testBinding)
+	suite("Diagnose", testDiagnose)
+	suite("Effective", testEffective)
+	suite("OpenTelemetry", testOpenTelemetry)
+	suite("Preflight", testPreflight)
 	suite("Properties", testProperties)
+	suite("Provenance", testProvenance)
+	suite("UniqueHostID", testUniqueHostID)
 	suite.Run(t)
 }
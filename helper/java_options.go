@@ -0,0 +1,185 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// JavaPropertyKeyPrefix marks binding keys that are passed to the Java agent as system properties rather than
+// environment variables.
+const JavaPropertyKeyPrefix = "jvm."
+
+// JavaPropertyPrefix is the prefix of the Java agent system properties.
+const JavaPropertyPrefix = "appdynamics."
+
+var javaPropertyName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// JavaPropertyName returns the fully qualified name of a Java agent system property, adding the appdynamics. prefix if
+// it is missing.
+func JavaPropertyName(name string) (string, error) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "-D")
+
+	if !javaPropertyName.MatchString(name) {
+		return "", fmt.Errorf("invalid Java system property name %q", name)
+	}
+
+	if !strings.HasPrefix(name, JavaPropertyPrefix) {
+		name = JavaPropertyPrefix + name
+	}
+
+	return name, nil
+}
+
+// ParseJavaOptions parses a comma-separated list of name=value pairs, e.g. from $BPL_APPD_JAVA_OPTS, into Java agent
+// system properties. A value containing commas must be quoted with single or double quotes, or the commas escaped
+// with a backslash.
+func ParseJavaOptions(s string) (map[string]string, error) {
+	tokens, err := split(s, func(r rune) bool { return r == ',' }, true)
+	if err != nil {
+		return nil, err
+	}
+
+	properties := map[string]string{}
+	for _, t := range tokens {
+		if strings.TrimSpace(t.Value) == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(t.Value, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid Java system property %q, must be name=value", strings.TrimSpace(t.Raw))
+		}
+
+		name, err := JavaPropertyName(k)
+		if err != nil {
+			return nil, err
+		}
+
+		properties[name] = strings.TrimSpace(v)
+	}
+
+	return properties, nil
+}
+
+// JavaOption returns a -D option for a system property, quoted so that the JVM reads a value containing whitespace or
+// quotes from $JAVA_TOOL_OPTIONS as a single option.
+func JavaOption(name string, value string) (string, error) {
+	o := fmt.Sprintf("-D%s=%s", name, value)
+
+	switch {
+	case !strings.ContainsAny(value, " \t\n\r\"'"):
+		return o, nil
+	case !strings.Contains(value, `"`):
+		return fmt.Sprintf(`"%s"`, o), nil
+	case !strings.Contains(value, "'"):
+		return fmt.Sprintf("'%s'", o), nil
+	default:
+		return "", fmt.Errorf("unable to quote value of Java system property %s, must not contain both single and double quotes", name)
+	}
+}
+
+// JavaOptionSet returns whether a system property is already set in $JAVA_TOOL_OPTIONS.
+func JavaOptionSet(name string) bool {
+	for _, o := range JavaToolOptions() {
+		if strings.HasPrefix(o, fmt.Sprintf("-D%s=", name)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JavaToolOptions returns the options in $JAVA_TOOL_OPTIONS, split the way the JVM splits them on whitespace outside
+// of single or double quotes, with the quotes removed.
+func JavaToolOptions() []string {
+	return SplitJavaToolOptions(os.Getenv("JAVA_TOOL_OPTIONS"))
+}
+
+// SplitJavaToolOptions splits s, a value of $JAVA_TOOL_OPTIONS, into options the way the JVM does.
+func SplitJavaToolOptions(s string) []string {
+	tokens, _ := split(s, unicode.IsSpace, false)
+
+	options := make([]string, len(tokens))
+	for i, t := range tokens {
+		options[i] = t.Value
+	}
+
+	return options
+}
+
+type token struct {
+	Raw   string
+	Value string
+}
+
+// split splits s on the runes matching separator outside of single or double quotes. Each token records both its raw
+// text and its value with the quotes, and the backslashes if escape is set, removed.
+func split(s string, separator func(rune) bool, escape bool) ([]token, error) {
+	var (
+		tokens  []token
+		value   strings.Builder
+		start   = -1
+		quote   rune
+		escaped bool
+	)
+
+	for i, r := range s {
+		if start < 0 && (escaped || quote != 0 || !separator(r)) {
+			start = i
+		}
+
+		switch {
+		case escaped:
+			value.WriteRune(r)
+			escaped = false
+		case escape && r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			value.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+		case separator(r):
+			if start >= 0 {
+				tokens = append(tokens, token{Raw: s[start:i], Value: value.String()})
+				value.Reset()
+				start = -1
+			}
+		default:
+			value.WriteRune(r)
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, token{Raw: s[start:], Value: value.String()})
+	}
+
+	switch {
+	case escaped:
+		return tokens, fmt.Errorf("invalid trailing escape")
+	case quote != 0:
+		return tokens, fmt.Errorf("unterminated %c quote", quote)
+	default:
+		return tokens, nil
+	}
+}
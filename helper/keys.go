@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper
+
+import (
+	"sort"
+	"strings"
+)
+
+// KnownKeys are the normalised binding keys read by the agents.
+var KnownKeys = map[string]bool{
+	"agent-account-access-key":  true,
+	"agent-account-name":        true,
+	"agent-application-name":    true,
+	"agent-global-account-name": true,
+	"agent-node-name":           true,
+	"agent-tier-name":           true,
+	"agent-unique-host-id":      true,
+	"controller-host-name":      true,
+	"controller-keystore":       true,
+	"controller-port":           true,
+	"controller-ssl-enabled":    true,
+}
+
+// KeyAliases maps well-known alternative binding keys onto the normalised key.
+var KeyAliases = map[string]string{
+	"access-key":         "agent-account-access-key",
+	"account-access-key": "agent-account-access-key",
+	"account-name":       "agent-account-name",
+	"application-name":   "agent-application-name",
+	"host":               "controller-host-name",
+	"host-name":          "controller-host-name",
+	"node-name":          "agent-node-name",
+	"port":               "controller-port",
+	"ssl-enabled":        "controller-ssl-enabled",
+	"tier-name":          "agent-tier-name",
+}
+
+// NormaliseKey returns the normalised form of a binding key. Keys are lower-cased with underscores replaced by
+// hyphens, an existing appdynamics- prefix is removed, and aliases are resolved, so that APPDYNAMICS_CONTROLLER_HOST_NAME,
+// controller-host-name, and host are equivalent.
+func NormaliseKey(key string) string {
+	s := strings.ToLower(key)
+	s = strings.ReplaceAll(s, "_", "-")
+	s = strings.TrimPrefix(s, "appdynamics-")
+
+	suffix := ""
+	if strings.HasSuffix(s, FileSuffix) {
+		s, suffix = strings.TrimSuffix(s, FileSuffix), FileSuffix
+	}
+
+	if a, ok := KeyAliases[s]; ok {
+		s = a
+	}
+
+	return s + suffix
+}
+
+// NormaliseKeys returns a map of normalised keys to the keys of secret. If more than one key normalises to the same
+// key, a key that is already normalised is preferred, otherwise the first in lexical order.
+func NormaliseKeys(secret map[string]string) map[string]string {
+	var raw []string
+	for k := range secret {
+		raw = append(raw, k)
+	}
+	sort.Strings(raw)
+
+	keys := map[string]string{}
+	for _, k := range raw {
+		n := NormaliseKey(k)
+		if existing, ok := keys[n]; !ok || (existing != n && k == n) {
+			keys[n] = k
+		}
+	}
+
+	return keys
+}
+
+// Known returns whether a normalised key is read by the agents.
+func Known(key string) bool {
+	return KnownKeys[strings.TrimSuffix(key, FileSuffix)]
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/bindings"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+// OpenTelemetryKeyPrefix marks keys of the AppDynamics binding that configure the OpenTelemetry exporter rather than
+// the agent.
+const OpenTelemetryKeyPrefix = "opentelemetry-"
+
+// OpenTelemetryKeys maps the keys of an opentelemetry binding, or of the AppDynamics binding without the
+// OpenTelemetryKeyPrefix, onto the OpenTelemetry SDK environment variables.
+var OpenTelemetryKeys = map[string]string{
+	"endpoint":            "OTEL_EXPORTER_OTLP_ENDPOINT",
+	"headers":             "OTEL_EXPORTER_OTLP_HEADERS",
+	"protocol":            "OTEL_EXPORTER_OTLP_PROTOCOL",
+	"resource-attributes": "OTEL_RESOURCE_ATTRIBUTES",
+}
+
+// OpenTelemetry enables the OpenTelemetry dual mode of the Java agent, so that it exports OpenTelemetry traces
+// alongside the AppDynamics data. The exporter is configured from a binding of type opentelemetry, or from the
+// opentelemetry- keys of the AppDynamics binding.
+type OpenTelemetry struct {
+	Bindings libcnb.Bindings
+	Logger   bard.Logger
+}
+
+func (o OpenTelemetry) Execute() (map[string]string, error) {
+	if !sherpa.ResolveBool("BPL_APPD_OTEL_ENABLED") || !JavaAgentConfigured() {
+		return nil, nil
+	}
+
+	if ok, err := Deactivated(o.Bindings); err != nil {
+		return nil, err
+	} else if ok {
+		return nil, nil
+	}
+
+	appd, _, err := ResolveBinding(o.Bindings, "BPL_APPD_BINDING_NAME")
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve binding AppDynamics\n%w", err)
+	}
+
+	otel, _, err := bindings.ResolveOne(o.Bindings, bindings.OfType("opentelemetry"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve binding opentelemetry\n%w", err)
+	}
+
+	settings := map[string]string{}
+	for k, v := range appd.Secret {
+		if strings.HasPrefix(k, OpenTelemetryKeyPrefix) {
+			settings[strings.TrimPrefix(k, OpenTelemetryKeyPrefix)] = strings.TrimSpace(v)
+		}
+	}
+	for k, v := range otel.Secret {
+		settings[k] = strings.TrimSpace(v)
+	}
+
+	settings["resource-attributes"] = ResourceAttributes(appd, settings["resource-attributes"])
+
+	binding, err := BindingPrecedence()
+	if err != nil {
+		return nil, err
+	}
+
+	e := map[string]string{}
+	if _, ok := os.LookupEnv("OTEL_TRACES_EXPORTER"); !ok {
+		e["OTEL_TRACES_EXPORTER"] = "otlp"
+	}
+
+	for k, name := range OpenTelemetryKeys {
+		v, ok := settings[k]
+		if !ok || v == "" {
+			continue
+		}
+
+		if _, ok := os.LookupEnv(name); ok && !binding {
+			continue
+		}
+
+		e[name] = v
+	}
+
+	if !JavaOptionSet("appdynamics.opentelemetry.enabled") {
+		e["JAVA_TOOL_OPTIONS"] = sherpa.AppendToEnvVar("JAVA_TOOL_OPTIONS", " ", "-Dappdynamics.opentelemetry.enabled=true")
+	}
+
+	endpoint, ok := e["OTEL_EXPORTER_OTLP_ENDPOINT"]
+	if !ok {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+
+	if endpoint == "" {
+		o.Logger.Info("Enabling AppDynamics OpenTelemetry dual mode")
+	} else {
+		o.Logger.Infof("Enabling AppDynamics OpenTelemetry dual mode exporting to %s", endpoint)
+	}
+
+	return e, nil
+}
+
+// ResourceAttributes returns the OpenTelemetry resource attributes, defaulting service.name and service.namespace to
+// the tier and application names of the agent so that traces are correlated with the node.
+func ResourceAttributes(binding libcnb.Binding, configured string) string {
+	attributes := map[string]string{}
+
+	if s := ResolveSetting(binding, "agent-tier-name"); s != "" {
+		attributes["service.name"] = s
+	}
+	if s := ResolveSetting(binding, "agent-application-name"); s != "" {
+		attributes["service.namespace"] = s
+	}
+
+	for _, a := range strings.Split(configured, ",") {
+		if k, v, ok := strings.Cut(a, "="); ok {
+			attributes[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+
+	var pairs []string
+	for k, v := range attributes {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper_test
+
+import (
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/helper"
+)
+
+func testOpenTelemetry(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		o helper.OpenTelemetry
+	)
+
+	it.Before(func() {
+		t.Setenv("BPL_APPD_OTEL_ENABLED", "true")
+		t.Setenv("JAVA_TOOL_OPTIONS", "-javaagent:/layers/test/appdynamics-java/javaagent.jar")
+
+		o.Bindings = libcnb.Bindings{
+			{
+				Name: "test-binding",
+				Type: "AppDynamics",
+				Secret: map[string]string{
+					"agent-application-name": "test-application",
+					"agent-tier-name":        "test-tier",
+					"opentelemetry-endpoint": "https://test-appd-collector:4318",
+					"opentelemetry-headers":  "api-key=test-appd-key",
+					"opentelemetry-protocol": "http/protobuf",
+				},
+			},
+		}
+	})
+
+	it("does not contribute unless $BPL_APPD_OTEL_ENABLED is set", func() {
+		t.Setenv("BPL_APPD_OTEL_ENABLED", "false")
+
+		Expect(o.Execute()).To(BeNil())
+	})
+
+	it("does not contribute unless the Java agent is configured", func() {
+		t.Setenv("JAVA_TOOL_OPTIONS", "-Xmx1g")
+
+		Expect(o.Execute()).To(BeNil())
+	})
+
+	it("does not contribute if the agent is deactivated", func() {
+		t.Setenv("BPI_APPD_DEACTIVATE_UNCONFIGURED", "true")
+		o.Bindings = libcnb.Bindings{}
+
+		Expect(o.Execute()).To(BeNil())
+	})
+
+	it("configures exporter from AppDynamics binding", func() {
+		Expect(o.Execute()).To(Equal(map[string]string{
+			"JAVA_TOOL_OPTIONS":           "-javaagent:/layers/test/appdynamics-java/javaagent.jar -Dappdynamics.opentelemetry.enabled=true",
+			"OTEL_EXPORTER_OTLP_ENDPOINT": "https://test-appd-collector:4318",
+			"OTEL_EXPORTER_OTLP_HEADERS":  "api-key=test-appd-key",
+			"OTEL_EXPORTER_OTLP_PROTOCOL": "http/protobuf",
+			"OTEL_RESOURCE_ATTRIBUTES":    "service.name=test-tier,service.namespace=test-application",
+			"OTEL_TRACES_EXPORTER":        "otlp",
+		}))
+	})
+
+	it("prefers opentelemetry binding", func() {
+		o.Bindings = append(o.Bindings, libcnb.Binding{
+			Name: "test-opentelemetry",
+			Type: "opentelemetry",
+			Secret: map[string]string{
+				"endpoint":            "https://test-collector:4317",
+				"resource-attributes": "service.name=test-service, deployment.environment=test",
+			},
+		})
+
+		e, err := o.Execute()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(e).To(HaveKeyWithValue("OTEL_EXPORTER_OTLP_ENDPOINT", "https://test-collector:4317"))
+		Expect(e).To(HaveKeyWithValue("OTEL_EXPORTER_OTLP_HEADERS", "api-key=test-appd-key"))
+		Expect(e).To(HaveKeyWithValue("OTEL_RESOURCE_ATTRIBUTES",
+			"deployment.environment=test,service.name=test-service,service.namespace=test-application"))
+	})
+
+	it("does not override explicit environment", func() {
+		t.Setenv("JAVA_TOOL_OPTIONS", "-javaagent:/layers/test/appdynamics-java/javaagent.jar -Dappdynamics.opentelemetry.enabled=true")
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "https://test-explicit-collector:4317")
+		t.Setenv("OTEL_TRACES_EXPORTER", "console")
+
+		e, err := o.Execute()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(e).NotTo(HaveKey("JAVA_TOOL_OPTIONS"))
+		Expect(e).NotTo(HaveKey("OTEL_EXPORTER_OTLP_ENDPOINT"))
+		Expect(e).NotTo(HaveKey("OTEL_TRACES_EXPORTER"))
+	})
+
+	it("overrides explicit environment with $BPL_APPD_BINDING_PRECEDENCE=binding", func() {
+		t.Setenv("BPL_APPD_BINDING_PRECEDENCE", "binding")
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "https://test-explicit-collector:4317")
+
+		Expect(o.Execute()).To(HaveKeyWithValue("OTEL_EXPORTER_OTLP_ENDPOINT", "https://test-appd-collector:4318"))
+	})
+
+	it("defaults resource attributes with the precedence of $BPL_APPD_BINDING_PRECEDENCE", func() {
+		t.Setenv("APPDYNAMICS_AGENT_TIER_NAME", "test-env-tier")
+
+		Expect(o.Execute()).To(HaveKeyWithValue("OTEL_RESOURCE_ATTRIBUTES", "service.name=test-env-tier,service.namespace=test-application"))
+
+		t.Setenv("BPL_APPD_BINDING_PRECEDENCE", "binding")
+
+		Expect(o.Execute()).To(HaveKeyWithValue("OTEL_RESOURCE_ATTRIBUTES", "service.name=test-tier,service.namespace=test-application"))
+	})
+
+	it("is not exported by properties", func() {
+		p := helper.Properties{Bindings: o.Bindings}
+
+		Expect(p.Execute()).To(Equal(map[string]string{
+			"APPDYNAMICS_AGENT_APPLICATION_NAME": "test-application",
+			"APPDYNAMICS_AGENT_TIER_NAME":        "test-tier",
+		}))
+	})
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+// Preflight checks that the controller is reachable before the application starts so that a misconfigured host or
+// port is reported immediately rather than in the agent logs.
+type Preflight struct {
+	Bindings libcnb.Bindings
+	Logger   bard.Logger
+
+	// RootCAs are the certificate authorities used to verify the controller. The system pool is used if nil.
+	RootCAs *x509.CertPool
+}
+
+func (p Preflight) Execute() (map[string]string, error) {
+	mode := sherpa.GetEnvWithDefault("BPL_APPD_PREFLIGHT", "false")
+	switch mode {
+	case "false":
+		return nil, nil
+	case "true", "strict":
+	default:
+		return nil, fmt.Errorf("unsupported $BPL_APPD_PREFLIGHT %s, must be one of false, true, or strict", mode)
+	}
+
+	timeout, err := time.ParseDuration(sherpa.GetEnvWithDefault("BPL_APPD_PREFLIGHT_TIMEOUT", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse $BPL_APPD_PREFLIGHT_TIMEOUT\n%w", err)
+	}
+
+	if ok, err := Deactivated(p.Bindings); err != nil {
+		return nil, err
+	} else if ok {
+		return nil, nil
+	}
+
+	b, _, err := ResolveBinding(p.Bindings, "BPL_APPD_BINDING_NAME")
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve binding AppDynamics\n%w", err)
+	}
+
+	if ResolveSetting(b, "controller-host-name") == "" {
+		p.Logger.Info("Skipping AppDynamics preflight, no controller host configured")
+		return nil, nil
+	}
+
+	target, err := p.Check(b, timeout)
+	if err != nil {
+		if mode == "strict" {
+			return nil, fmt.Errorf("AppDynamics preflight failed for %s\n%w", target, err)
+		}
+
+		p.Logger.Infof("AppDynamics preflight failed for %s: %s", target, strings.ReplaceAll(err.Error(), "\n", ": "))
+		return nil, nil
+	}
+
+	p.Logger.Infof("AppDynamics preflight passed for %s", target)
+	return nil, nil
+}
+
+// Check resolves the controller host, connects to it, and, if $BPL_APPD_PREFLIGHT_STATUS is set, calls its status
+// endpoint. It returns a description of the controller that was checked.
+func (p Preflight) Check(binding libcnb.Binding, timeout time.Duration) (string, error) {
+	host := ResolveSetting(binding, "controller-host-name")
+	if host == "" {
+		return "controller", fmt.Errorf("no controller host configured")
+	}
+
+	ssl, _ := strconv.ParseBool(ResolveSetting(binding, "controller-ssl-enabled"))
+
+	port := ResolveSetting(binding, "controller-port")
+	if port == "" && ssl {
+		port = "443"
+	} else if port == "" {
+		port = "80"
+	}
+
+	address := net.JoinHostPort(host, port)
+	target := address
+	if ssl {
+		target = fmt.Sprintf("%s (TLS)", address)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+		return target, fmt.Errorf("unable to resolve %s\n%w", host, err)
+	}
+
+	tlsConfig := &tls.Config{RootCAs: p.RootCAs, ServerName: host, MinVersion: tls.VersionTLS12}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	if ssl {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return target, fmt.Errorf("unable to connect to %s\n%w", address, err)
+	}
+	conn.Close()
+
+	if !sherpa.ResolveBool("BPL_APPD_PREFLIGHT_STATUS") {
+		return target, nil
+	}
+
+	scheme := "http"
+	if ssl {
+		scheme = "https"
+	}
+
+	client := http.Client{Timeout: timeout, Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	uri := fmt.Sprintf("%s://%s/controller/rest/serverstatus", scheme, address)
+
+	resp, err := client.Get(uri)
+	if err != nil {
+		return target, fmt.Errorf("unable to request %s\n%w", uri, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return target, fmt.Errorf("controller status %s returned %d", uri, resp.StatusCode)
+	}
+
+	return target, nil
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper_test
+
+import (
+	"bytes"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/helper"
+)
+
+func testPreflight(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		buffer *bytes.Buffer
+		p      helper.Preflight
+		server *httptest.Server
+		status int
+	)
+
+	it.Before(func() {
+		status = http.StatusOK
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/controller/rest/serverstatus" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(status)
+		}))
+
+		host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+
+		pool := x509.NewCertPool()
+		pool.AddCert(server.Certificate())
+
+		buffer = bytes.NewBuffer(nil)
+		p = helper.Preflight{
+			Bindings: libcnb.Bindings{
+				{
+					Name: "test-binding",
+					Type: "AppDynamics",
+					Secret: map[string]string{
+						"controller-host-name":   host,
+						"controller-port":        port,
+						"controller-ssl-enabled": "true",
+					},
+				},
+			},
+			Logger:  bard.NewLogger(buffer),
+			RootCAs: pool,
+		}
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	it("does nothing by default", func() {
+		Expect(p.Execute()).To(BeNil())
+		Expect(buffer.String()).To(BeEmpty())
+	})
+
+	it("fails with unsupported $BPL_APPD_PREFLIGHT", func() {
+		t.Setenv("BPL_APPD_PREFLIGHT", "test-mode")
+
+		_, err := p.Execute()
+		Expect(err).To(MatchError("unsupported $BPL_APPD_PREFLIGHT test-mode, must be one of false, true, or strict"))
+	})
+
+	it("passes with reachable controller", func() {
+		t.Setenv("BPL_APPD_PREFLIGHT", "true")
+		t.Setenv("BPL_APPD_PREFLIGHT_STATUS", "true")
+
+		Expect(p.Execute()).To(BeNil())
+		Expect(buffer.String()).To(ContainSubstring("AppDynamics preflight passed for %s (TLS)", server.Listener.Addr().String()))
+	})
+
+	it("checks binding controller with $BPL_APPD_BINDING_PRECEDENCE=binding", func() {
+		t.Setenv("BPL_APPD_PREFLIGHT", "strict")
+		t.Setenv("APPDYNAMICS_CONTROLLER_HOST_NAME", "test-env-host.invalid")
+
+		_, err := p.Execute()
+		Expect(err).To(MatchError(ContainSubstring("test-env-host.invalid")))
+
+		t.Setenv("BPL_APPD_BINDING_PRECEDENCE", "binding")
+
+		Expect(p.Execute()).To(BeNil())
+		Expect(buffer.String()).To(ContainSubstring("AppDynamics preflight passed for %s (TLS)", server.Listener.Addr().String()))
+	})
+
+	it("logs failure without blocking startup", func() {
+		t.Setenv("BPL_APPD_PREFLIGHT", "true")
+		t.Setenv("BPL_APPD_PREFLIGHT_STATUS", "true")
+		status = http.StatusServiceUnavailable
+
+		Expect(p.Execute()).To(BeNil())
+		Expect(buffer.String()).To(ContainSubstring("AppDynamics preflight failed for"))
+		Expect(buffer.String()).To(ContainSubstring("returned 503"))
+	})
+
+	it("fails startup when strict", func() {
+		t.Setenv("BPL_APPD_PREFLIGHT", "strict")
+		p.RootCAs = x509.NewCertPool()
+
+		_, err := p.Execute()
+		Expect(err).To(MatchError(ContainSubstring("AppDynamics preflight failed for")))
+	})
+
+	it("skips without controller host", func() {
+		t.Setenv("BPL_APPD_PREFLIGHT", "strict")
+		p.Bindings = nil
+
+		Expect(p.Execute()).To(BeNil())
+		Expect(buffer.String()).To(ContainSubstring("Skipping AppDynamics preflight, no controller host configured"))
+	})
+
+	it("skips if the agent is deactivated", func() {
+		t.Setenv("BPL_APPD_PREFLIGHT", "strict")
+		t.Setenv("BPI_APPD_DEACTIVATE_UNCONFIGURED", "true")
+		p.Bindings = nil
+
+		Expect(p.Execute()).To(BeNil())
+		Expect(buffer.String()).To(BeEmpty())
+	})
+}
@@ -18,36 +18,218 @@ package helper
 
 import (
 	"fmt"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/buildpacks/libcnb"
+	"github.com/heroku/color"
 	"github.com/paketo-buildpacks/libpak/bard"
-	"github.com/paketo-buildpacks/libpak/bindings"
+	"github.com/paketo-buildpacks/libpak/sherpa"
 )
 
+// DefaultPrefix prefixes the variables recording the launch defaults contributed at build time, so that a default is
+// not mistaken for a value set explicitly in the environment.
+const DefaultPrefix = "BPI_APPD_DEFAULT_"
+
+// FileSuffix marks binding keys whose values are exported as the path of the binding file rather than inline.
+const FileSuffix = "-file"
+
+// JavaFileProperties maps binding keys that are always exported as file paths onto the Java agent system property that
+// reads the path.
+var JavaFileProperties = map[string]string{
+	"controller-keystore": "appdynamics.controller.keystoreFilename",
+}
+
 type Properties struct {
 	Bindings libcnb.Bindings
 	Logger   bard.Logger
 }
 
 func (p Properties) Execute() (map[string]string, error) {
-	b, ok, err := bindings.ResolveOne(p.Bindings, bindings.OfType("AppDynamics"))
+	if ok, err := Deactivated(p.Bindings); err != nil {
+		return nil, err
+	} else if ok {
+		e := Deactivate()
+		if len(e) == 0 {
+			return nil, nil
+		}
+
+		p.Logger.Info("Deactivating AppDynamics, no binding of type AppDynamics or controller host name found")
+		return e, nil
+	}
+
+	b, ok, err := ResolveBinding(p.Bindings, "BPL_APPD_BINDING_NAME")
 	if err != nil {
 		return nil, fmt.Errorf("unable to resolve binding AppDynamics\n%w", err)
 	} else if !ok {
 		return nil, nil
 	}
 
+	binding, err := BindingPrecedence()
+	if err != nil {
+		return nil, err
+	}
+
 	p.Logger.Info("Configuring AppDynamics properties")
 
-	e := make(map[string]string, len(b.Secret))
+	fileKeys := map[string]bool{}
+	for k := range JavaFileProperties {
+		fileKeys[k] = true
+	}
+	for _, k := range strings.Split(os.Getenv("BPL_APPD_FILE_KEYS"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			fileKeys[NormaliseKey(k)] = true
+		}
+	}
+
+	properties, err := ParseJavaOptions(os.Getenv("BPL_APPD_JAVA_OPTS"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse $BPL_APPD_JAVA_OPTS\n%w", err)
+	}
+
+	var (
+		opts    []string
+		skipped []string
+		unknown []string
+	)
+	secret := make(map[string]string, len(b.Secret))
 	for k, v := range b.Secret {
-		s := strings.ToUpper(k)
-		s = strings.ReplaceAll(s, "-", "_")
-		s = strings.ReplaceAll(s, ".", "_")
+		if strings.HasPrefix(k, OpenTelemetryKeyPrefix) {
+			continue
+		}
+
+		if !strings.HasPrefix(k, JavaPropertyKeyPrefix) {
+			secret[k] = v
+			continue
+		}
+
+		name, err := JavaPropertyName(strings.TrimPrefix(k, JavaPropertyKeyPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("unable to map binding key %s to a Java system property\n%w", k, err)
+		}
+
+		if _, ok := properties[name]; !binding && (ok || JavaOptionSet(name)) {
+			skipped = append(skipped, "-D"+name)
+			continue
+		}
+		properties[name] = strings.TrimSpace(v)
+	}
+
+	e := make(map[string]string, len(b.Secret))
+	for k, raw := range NormaliseKeys(secret) {
+		v := b.Secret[raw]
+		file := strings.HasSuffix(k, FileSuffix) || fileKeys[k] || strings.Contains(strings.TrimSpace(v), "\n")
+
+		if !Known(k) && !fileKeys[k] {
+			unknown = append(unknown, raw)
+		}
+
+		s := k
+		if file && !strings.HasSuffix(k, FileSuffix) {
+			s += FileSuffix
+		}
+		s = EnvironmentName(s)
+
+		if !binding && Explicit(s) {
+			skipped = append(skipped, s)
+			continue
+		}
+
+		if !file {
+			e[s] = v
+			continue
+		}
 
-		e[fmt.Sprintf("APPDYNAMICS_%s", s)] = v
+		path, _ := b.SecretFilePath(raw)
+		e[s] = path
+
+		if prop, ok := JavaFileProperties[strings.TrimSuffix(k, FileSuffix)]; ok {
+			opts = append(opts, fmt.Sprintf("-D%s=%s", prop, path))
+		}
+	}
+
+	sort.Strings(opts)
+	var names []string
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o, err := JavaOption(name, properties[name])
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, o)
+	}
+
+	if len(opts) > 0 {
+		e["JAVA_TOOL_OPTIONS"] = sherpa.AppendToEnvVar("JAVA_TOOL_OPTIONS", " ", opts...)
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		p.Logger.Infof("%s: unknown binding keys %s are exported without being recognised by the agent",
+			color.YellowString("Warning"), strings.Join(unknown, ", "))
+	}
+
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		p.Logger.Infof("Skipping binding values for %s set in the environment, set $BPL_APPD_BINDING_PRECEDENCE=binding to use the binding values",
+			strings.Join(skipped, ", "))
 	}
 
 	return e, nil
 }
+
+// BindingPrecedence returns whether binding values take precedence over values set explicitly in the environment, from
+// $BPL_APPD_BINDING_PRECEDENCE.
+func BindingPrecedence() (bool, error) {
+	switch s := sherpa.GetEnvWithDefault("BPL_APPD_BINDING_PRECEDENCE", "env"); s {
+	case "binding":
+		return true, nil
+	case "env":
+		return false, nil
+	default:
+		return false, fmt.Errorf("unsupported $BPL_APPD_BINDING_PRECEDENCE %s, must be one of binding or env", s)
+	}
+}
+
+// ResolveSetting returns the value of the normalised key from the binding or the environment with the precedence of
+// $BPL_APPD_BINDING_PRECEDENCE, as the properties helper exports it. Helpers that run before the properties helper use it
+// to see the same value.
+func ResolveSetting(binding libcnb.Binding, key string) string {
+	name := EnvironmentName(key)
+	raw, ok := NormaliseKeys(binding.Secret)[key]
+
+	if b, _ := BindingPrecedence(); !ok || (!b && Explicit(name)) {
+		return os.Getenv(name)
+	}
+
+	return binding.Secret[raw]
+}
+
+// EnvironmentName returns the name of the environment variable for a binding key.
+func EnvironmentName(key string) string {
+	s := strings.ToUpper(key)
+	s = strings.ReplaceAll(s, "-", "_")
+	s = strings.ReplaceAll(s, ".", "_")
+
+	return fmt.Sprintf("APPDYNAMICS_%s", s)
+}
+
+// DefaultName returns the name of the variable recording the launch default of name.
+func DefaultName(name string) string {
+	return DefaultPrefix + strings.TrimPrefix(name, "APPDYNAMICS_")
+}
+
+// Explicit returns whether name is set in the environment to a value other than its launch default.
+func Explicit(name string) bool {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return false
+	}
+
+	d, ok := os.LookupEnv(DefaultName(name))
+	return !ok || v != d
+}
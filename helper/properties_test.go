@@ -17,10 +17,12 @@
 package helper_test
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/buildpacks/libcnb"
 	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/sclevine/spec"
 
 	"github.com/paketo-buildpacks/appdynamics/v4/helper"
@@ -37,6 +39,39 @@ func testProperties(t *testing.T, context spec.G, it spec.S) {
 		Expect(p.Execute()).To(BeNil())
 	})
 
+	context("deactivation", func() {
+		it.Before(func() {
+			t.Setenv("BPI_APPD_DEACTIVATE_UNCONFIGURED", "true")
+			t.Setenv("JAVA_TOOL_OPTIONS", `-Xmx1g "-Dtest.quoted=test value" -javaagent:/layers/test/appdynamics-java/javaagent.jar "-Dappdynamics.agent.conf.dir=/layers/test/appdynamics java-configuration/conf"`)
+			t.Setenv("PHP_INI_SCAN_DIR", "/layers/test/appdynamics-php/php.ini.d:/workspace/.php.ini.d")
+		})
+
+		it("deactivates agents if no binding exists", func() {
+			Expect(p.Execute()).To(Equal(map[string]string{
+				"JAVA_TOOL_OPTIONS": `-Xmx1g "-Dtest.quoted=test value"`,
+				"PHP_INI_SCAN_DIR":  "/workspace/.php.ini.d",
+			}))
+		})
+
+		it("does not deactivate agents if the agent was contributed with a binding", func() {
+			t.Setenv("BPI_APPD_DEACTIVATE_UNCONFIGURED", "false")
+
+			Expect(p.Execute()).To(BeNil())
+		})
+
+		it("does not deactivate agents if controller is configured in environment", func() {
+			t.Setenv("APPDYNAMICS_CONTROLLER_HOST_NAME", "test-host")
+
+			Expect(p.Execute()).To(BeNil())
+		})
+
+		it("does not deactivate agents if controller is configured in $JAVA_TOOL_OPTIONS", func() {
+			t.Setenv("JAVA_TOOL_OPTIONS", `-javaagent:/layers/test/appdynamics-java/javaagent.jar '-Dappdynamics.controller.hostName=test-host'`)
+
+			Expect(p.Execute()).To(BeNil())
+		})
+	})
+
 	it("contributes properties if binding exists", func() {
 		p.Bindings = libcnb.Bindings{
 			{
@@ -50,4 +85,212 @@ func testProperties(t *testing.T, context spec.G, it spec.S) {
 			"APPDYNAMICS_TEST_KEY": "test-value",
 		}))
 	})
+
+	context("file references", func() {
+		it.Before(func() {
+			p.Bindings = libcnb.Bindings{
+				{
+					Name: "test-binding",
+					Path: "/test/binding",
+					Type: "AppDynamics",
+					Secret: map[string]string{
+						"controller-keystore": "test-keystore",
+						"test-certificate":    "-----BEGIN CERTIFICATE-----\ntest\n-----END CERTIFICATE-----\n",
+						"test-key":            "test-value",
+						"test-key-file":       "test-value",
+						"test-listed":         "test-value",
+					},
+				},
+			}
+		})
+
+		it("exports file paths", func() {
+			t.Setenv("BPL_APPD_FILE_KEYS", "test-listed")
+			t.Setenv("JAVA_TOOL_OPTIONS", "test-java-tool-options")
+
+			Expect(p.Execute()).To(Equal(map[string]string{
+				"APPDYNAMICS_CONTROLLER_KEYSTORE_FILE": "/test/binding/controller-keystore",
+				"APPDYNAMICS_TEST_CERTIFICATE_FILE":    "/test/binding/test-certificate",
+				"APPDYNAMICS_TEST_KEY":                 "test-value",
+				"APPDYNAMICS_TEST_KEY_FILE":            "/test/binding/test-key-file",
+				"APPDYNAMICS_TEST_LISTED_FILE":         "/test/binding/test-listed",
+				"JAVA_TOOL_OPTIONS":                    "test-java-tool-options -Dappdynamics.controller.keystoreFilename=/test/binding/controller-keystore",
+			}))
+		})
+	})
+
+	context("key normalisation", func() {
+		var buffer *bytes.Buffer
+
+		it.Before(func() {
+			buffer = bytes.NewBuffer(nil)
+			p.Logger = bard.NewLogger(buffer)
+			p.Bindings = libcnb.Bindings{
+				{
+					Name: "test-binding",
+					Type: "AppDynamics",
+					Secret: map[string]string{
+						"APPDYNAMICS_AGENT_ACCOUNT_NAME": "test-account-name",
+						"access-key":                     "test-access-key",
+						"appdynamics-controller-port":    "443",
+						"host":                           "test-host",
+						"test-unknown":                   "test-value",
+					},
+				},
+			}
+		})
+
+		it("normalises prefixed and aliased keys", func() {
+			Expect(p.Execute()).To(Equal(map[string]string{
+				"APPDYNAMICS_AGENT_ACCOUNT_ACCESS_KEY": "test-access-key",
+				"APPDYNAMICS_AGENT_ACCOUNT_NAME":       "test-account-name",
+				"APPDYNAMICS_CONTROLLER_HOST_NAME":     "test-host",
+				"APPDYNAMICS_CONTROLLER_PORT":          "443",
+				"APPDYNAMICS_TEST_UNKNOWN":             "test-value",
+			}))
+		})
+
+		it("prefers normalised keys", func() {
+			p.Bindings[0].Secret["controller-host-name"] = "test-normalised-host"
+
+			Expect(p.Execute()).To(HaveKeyWithValue("APPDYNAMICS_CONTROLLER_HOST_NAME", "test-normalised-host"))
+		})
+
+		it("warns about unknown keys", func() {
+			_, err := p.Execute()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buffer.String()).To(ContainSubstring("unknown binding keys test-unknown are exported"))
+			Expect(buffer.String()).NotTo(ContainSubstring("access-key,"))
+		})
+	})
+
+	context("Java system properties", func() {
+		it.Before(func() {
+			p.Bindings = libcnb.Bindings{
+				{
+					Name: "test-binding",
+					Type: "AppDynamics",
+					Secret: map[string]string{
+						"jvm.agent.uniqueHostId":                       "test-host-id",
+						"jvm.appdynamics.socket.collection.bci.enable": "true",
+						"jvm.agent.maxMetrics":                         "5000",
+						"jvm.analytics.agent.url":                      "http://test host",
+					},
+				},
+			}
+		})
+
+		it("appends binding keys to $JAVA_TOOL_OPTIONS", func() {
+			t.Setenv("JAVA_TOOL_OPTIONS", "test-java-tool-options")
+
+			Expect(p.Execute()).To(Equal(map[string]string{
+				"JAVA_TOOL_OPTIONS": `test-java-tool-options -Dappdynamics.agent.maxMetrics=5000 -Dappdynamics.agent.uniqueHostId=test-host-id "-Dappdynamics.analytics.agent.url=http://test host" -Dappdynamics.socket.collection.bci.enable=true`,
+			}))
+		})
+
+		it("appends $BPL_APPD_JAVA_OPTS", func() {
+			t.Setenv("BPL_APPD_JAVA_OPTS", `agent.maxMetrics=10000, appdynamics.test.quoted=it\'s \"quoted\"`)
+
+			_, err := p.Execute()
+			Expect(err).To(MatchError(ContainSubstring("must not contain both single and double quotes")))
+
+			t.Setenv("BPL_APPD_JAVA_OPTS", `agent.maxMetrics=10000, -Dappdynamics.test.quoted=\"quoted\"`)
+
+			Expect(p.Execute()).To(HaveKeyWithValue("JAVA_TOOL_OPTIONS", `-Dappdynamics.agent.maxMetrics=10000 -Dappdynamics.agent.uniqueHostId=test-host-id "-Dappdynamics.analytics.agent.url=http://test host" -Dappdynamics.socket.collection.bci.enable=true '-Dappdynamics.test.quoted="quoted"'`))
+		})
+
+		it("parses quoted and escaped commas in $BPL_APPD_JAVA_OPTS", func() {
+			p.Bindings[0].Secret = map[string]string{}
+			t.Setenv("BPL_APPD_JAVA_OPTS", `agent.applicationName="test, application", agent.tierName='test, tier', agent.nodeName=test\, node`)
+
+			Expect(p.Execute()).To(HaveKeyWithValue("JAVA_TOOL_OPTIONS", `"-Dappdynamics.agent.applicationName=test, application" "-Dappdynamics.agent.nodeName=test, node" "-Dappdynamics.agent.tierName=test, tier"`))
+		})
+
+		it("fails with unterminated quotes in $BPL_APPD_JAVA_OPTS", func() {
+			t.Setenv("BPL_APPD_JAVA_OPTS", `agent.applicationName="test, application`)
+
+			_, err := p.Execute()
+			Expect(err).To(MatchError(ContainSubstring(`unterminated " quote`)))
+		})
+
+		it("does not override explicit environment", func() {
+			t.Setenv("JAVA_TOOL_OPTIONS", "-Dappdynamics.agent.uniqueHostId=test-explicit-host-id")
+			p.Bindings[0].Secret = map[string]string{"jvm.agent.uniqueHostId": "test-host-id"}
+
+			Expect(p.Execute()).To(BeEmpty())
+
+			t.Setenv("JAVA_TOOL_OPTIONS", `-Dtest.other="test value"  '-Dappdynamics.agent.uniqueHostId=test explicit host id'`)
+
+			Expect(p.Execute()).To(BeEmpty())
+		})
+
+		it("overrides explicit environment with $BPL_APPD_BINDING_PRECEDENCE=binding", func() {
+			t.Setenv("BPL_APPD_BINDING_PRECEDENCE", "binding")
+			t.Setenv("BPL_APPD_JAVA_OPTS", "agent.uniqueHostId=test-explicit-host-id")
+			p.Bindings[0].Secret = map[string]string{"jvm.agent.uniqueHostId": "test-host-id"}
+
+			Expect(p.Execute()).To(Equal(map[string]string{
+				"JAVA_TOOL_OPTIONS": "-Dappdynamics.agent.uniqueHostId=test-host-id",
+			}))
+		})
+
+		it("fails with invalid property names", func() {
+			p.Bindings[0].Secret = map[string]string{"jvm.agent.unique host": "test-host-id"}
+
+			_, err := p.Execute()
+			Expect(err).To(MatchError(ContainSubstring(`invalid Java system property name "agent.unique host"`)))
+		})
+	})
+
+	context("binding precedence", func() {
+		var buffer *bytes.Buffer
+
+		it.Before(func() {
+			buffer = bytes.NewBuffer(nil)
+			p.Logger = bard.NewLogger(buffer)
+			p.Bindings = libcnb.Bindings{
+				{
+					Name:   "test-binding",
+					Type:   "AppDynamics",
+					Secret: map[string]string{"agent-node-name": "test-binding-node", "agent-tier-name": "test-binding-tier"},
+				},
+			}
+		})
+
+		it("does not override explicit environment", func() {
+			t.Setenv("APPDYNAMICS_AGENT_NODE_NAME", "test-env-node")
+
+			Expect(p.Execute()).To(Equal(map[string]string{
+				"APPDYNAMICS_AGENT_TIER_NAME": "test-binding-tier",
+			}))
+			Expect(buffer.String()).To(ContainSubstring("Skipping binding values for APPDYNAMICS_AGENT_NODE_NAME"))
+		})
+
+		it("overrides launch defaults", func() {
+			t.Setenv("APPDYNAMICS_AGENT_TIER_NAME", "test-default-tier")
+			t.Setenv("BPI_APPD_DEFAULT_AGENT_TIER_NAME", "test-default-tier")
+
+			Expect(p.Execute()).To(Equal(map[string]string{
+				"APPDYNAMICS_AGENT_NODE_NAME": "test-binding-node",
+				"APPDYNAMICS_AGENT_TIER_NAME": "test-binding-tier",
+			}))
+		})
+
+		it("overrides explicit environment with $BPL_APPD_BINDING_PRECEDENCE=binding", func() {
+			t.Setenv("BPL_APPD_BINDING_PRECEDENCE", "binding")
+			t.Setenv("APPDYNAMICS_AGENT_NODE_NAME", "test-env-node")
+
+			Expect(p.Execute()).To(Equal(map[string]string{
+				"APPDYNAMICS_AGENT_NODE_NAME": "test-binding-node",
+				"APPDYNAMICS_AGENT_TIER_NAME": "test-binding-tier",
+			}))
+		})
+
+		it("fails with unsupported $BPL_APPD_BINDING_PRECEDENCE", func() {
+			t.Setenv("BPL_APPD_BINDING_PRECEDENCE", "test-precedence")
+
+			_, err := p.Execute()
+			Expect(err).To(MatchError("unsupported $BPL_APPD_BINDING_PRECEDENCE test-precedence, must be one of binding or env"))
+		})
+	})
 }
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+// ProvenanceFile is the name of the file in the provenance layer recording the build that contributed the agent.
+const ProvenanceFile = "provenance.properties"
+
+// ProvenancePrefix prefixes the Java agent system properties carrying the build provenance.
+const ProvenancePrefix = "appdynamics.build."
+
+// Provenance passes the build provenance recorded in the provenance layer to the Java agent as system properties so
+// that it is reported with the node.
+type Provenance struct {
+	Bindings libcnb.Bindings
+	Logger   bard.Logger
+}
+
+func (p Provenance) Execute() (map[string]string, error) {
+	path, ok := os.LookupEnv("BPI_APPD_PROVENANCE")
+	if !ok || !JavaAgentConfigured() {
+		return nil, nil
+	}
+
+	if ok, err := Deactivated(p.Bindings); err != nil {
+		return nil, err
+	} else if ok {
+		return nil, nil
+	}
+
+	properties, err := ReadProperties(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read build provenance\n%w", err)
+	}
+
+	var keys []string
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var opts []string
+	for _, k := range keys {
+		name := ProvenancePrefix + k
+		if JavaOptionSet(name) {
+			continue
+		}
+
+		o, err := JavaOption(name, properties[k])
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, o)
+	}
+
+	if len(opts) == 0 {
+		return nil, nil
+	}
+
+	p.Logger.Info("Configuring AppDynamics build provenance")
+	return map[string]string{"JAVA_TOOL_OPTIONS": sherpa.AppendToEnvVar("JAVA_TOOL_OPTIONS", " ", opts...)}, nil
+}
+
+// ReadProperties reads a file of key=value lines, ignoring blank lines and comments. A file that does not exist has no
+// properties.
+func ReadProperties(path string) (map[string]string, error) {
+	in, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	properties := map[string]string{}
+
+	s := bufio.NewScanner(in)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if k, v, ok := strings.Cut(line, "="); ok {
+			properties[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	return properties, nil
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/helper"
+)
+
+func testProvenance(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		p helper.Provenance
+	)
+
+	it.Before(func() {
+		file := filepath.Join(t.TempDir(), "provenance.properties")
+		Expect(os.WriteFile(file, []byte(`agent.version=test-agent-version
+buildpack.version=test-buildpack-version
+revision=test-revision
+timestamp=2023-11-14T22:13:20Z
+`), 0644)).To(Succeed())
+
+		t.Setenv("BPI_APPD_PROVENANCE", file)
+		t.Setenv("JAVA_TOOL_OPTIONS", "-javaagent:/layers/test/appdynamics-java/javaagent.jar")
+	})
+
+	it("passes provenance as system properties", func() {
+		Expect(p.Execute()).To(Equal(map[string]string{
+			"JAVA_TOOL_OPTIONS": "-javaagent:/layers/test/appdynamics-java/javaagent.jar " +
+				"-Dappdynamics.build.agent.version=test-agent-version " +
+				"-Dappdynamics.build.buildpack.version=test-buildpack-version " +
+				"-Dappdynamics.build.revision=test-revision " +
+				"-Dappdynamics.build.timestamp=2023-11-14T22:13:20Z",
+		}))
+	})
+
+	it("does not override explicit system properties", func() {
+		t.Setenv("JAVA_TOOL_OPTIONS", "-javaagent:/layers/test/appdynamics-java/javaagent.jar -Dappdynamics.build.revision=test-explicit-revision")
+
+		Expect(p.Execute()).To(HaveKeyWithValue("JAVA_TOOL_OPTIONS", Not(ContainSubstring("=test-revision"))))
+	})
+
+	it("does not contribute if agent is not configured", func() {
+		t.Setenv("JAVA_TOOL_OPTIONS", "-Xmx1g")
+
+		Expect(p.Execute()).To(BeNil())
+	})
+
+	it("does not contribute if the agent is deactivated", func() {
+		t.Setenv("BPI_APPD_DEACTIVATE_UNCONFIGURED", "true")
+
+		Expect(p.Execute()).To(BeNil())
+	})
+
+	it("does not contribute without provenance", func() {
+		Expect(os.Unsetenv("BPI_APPD_PROVENANCE")).To(Succeed())
+
+		Expect(p.Execute()).To(BeNil())
+	})
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/sherpa"
+)
+
+var (
+	cgroupContainerID    = regexp.MustCompile(`([0-9a-f]{64})(?:\.scope)?$`)
+	mountInfoContainerID = regexp.MustCompile(`containers/([0-9a-f]{64})/`)
+)
+
+// UniqueHostID sets the unique host ID of the agent to the container ID so that application nodes are correlated with
+// the machine and cluster agents monitoring the container. As the last helper to run, it also logs the effective
+// configuration, which then includes the values exported by the other helpers.
+type UniqueHostID struct {
+	Bindings      libcnb.Bindings
+	CgroupPath    string
+	Logger        bard.Logger
+	MountInfoPath string
+}
+
+func (u UniqueHostID) Execute() (map[string]string, error) {
+	e, err := u.uniqueHostID()
+	if err != nil {
+		return nil, err
+	}
+
+	environment := Environment(os.Environ())
+	for k, v := range e {
+		environment[k] = v
+	}
+	if err := LogEffectiveSettings(u.Logger, environment, u.Bindings); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (u UniqueHostID) uniqueHostID() (map[string]string, error) {
+	if _, ok := os.LookupEnv("BPL_APPD_UNIQUE_HOST_ID"); ok && !sherpa.ResolveBool("BPL_APPD_UNIQUE_HOST_ID") {
+		return nil, nil
+	}
+
+	if _, ok := os.LookupEnv("APPDYNAMICS_AGENT_UNIQUE_HOST_ID"); ok || JavaOptionSet("appdynamics.agent.uniqueHostId") {
+		return nil, nil
+	}
+
+	if ok, err := Deactivated(u.Bindings); err != nil {
+		return nil, err
+	} else if ok {
+		return nil, nil
+	}
+
+	java := JavaAgentConfigured()
+	if !java && filepath.Base(os.Getenv("BPI_APPD_AGENT_PATH")) == "appdynamics-php" {
+		u.Logger.Info("Skipping AppDynamics unique host ID, the PHP agent does not read $APPDYNAMICS_AGENT_UNIQUE_HOST_ID")
+		return nil, nil
+	}
+
+	id, source, err := u.ContainerID()
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine container ID\n%w", err)
+	} else if id == "" {
+		return nil, nil
+	}
+
+	u.Logger.Infof("Setting AppDynamics unique host ID to %s from %s", id, source)
+	e := map[string]string{"APPDYNAMICS_AGENT_UNIQUE_HOST_ID": id}
+
+	if java {
+		o, err := JavaOption("appdynamics.agent.uniqueHostId", id)
+		if err != nil {
+			return nil, err
+		}
+		e["JAVA_TOOL_OPTIONS"] = sherpa.AppendToEnvVar("JAVA_TOOL_OPTIONS", " ", o)
+	}
+
+	return e, nil
+}
+
+// ContainerID returns the ID of the container from the cgroup of the process for cgroup v1, from the mounts of the
+// process for cgroup v2, or from $HOSTNAME, and where it was found.
+func (u UniqueHostID) ContainerID() (id string, source string, err error) {
+	id, err = find(u.CgroupPath, func(line string) string {
+		// <hierarchy-id>:<controllers>:<path>
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			return ""
+		}
+
+		if m := cgroupContainerID.FindStringSubmatch(parts[2]); m != nil {
+			return m[1]
+		}
+		return ""
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read %s\n%w", u.CgroupPath, err)
+	} else if id != "" {
+		return id, u.CgroupPath, nil
+	}
+
+	id, err = find(u.MountInfoPath, func(line string) string {
+		if m := mountInfoContainerID.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+		return ""
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read %s\n%w", u.MountInfoPath, err)
+	} else if id != "" {
+		return id, u.MountInfoPath, nil
+	}
+
+	if s, ok := os.LookupEnv("HOSTNAME"); ok && s != "" {
+		return s, "$HOSTNAME", nil
+	}
+
+	return "", "", nil
+}
+
+// find returns the first non-empty match of the lines of a file. A file that does not exist has no matches.
+func find(path string, match func(line string) string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	in, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	s := bufio.NewScanner(in)
+	for s.Scan() {
+		if m := match(s.Text()); m != "" {
+			return m, nil
+		}
+	}
+
+	return "", s.Err()
+}
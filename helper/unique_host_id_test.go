@@ -0,0 +1,163 @@
+/*
+ * Copyright 2018-2025 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/appdynamics/v4/helper"
+)
+
+func testUniqueHostID(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		u helper.UniqueHostID
+	)
+
+	it.Before(func() {
+		t.Setenv("HOSTNAME", "test-hostname")
+
+		u = helper.UniqueHostID{
+			CgroupPath:    filepath.Join("testdata", "unique-host-id", "cgroup-v2"),
+			MountInfoPath: filepath.Join("testdata", "unique-host-id", "mountinfo-none"),
+		}
+	})
+
+	it("reads container ID from cgroup v1", func() {
+		u.CgroupPath = filepath.Join("testdata", "unique-host-id", "cgroup-v1")
+
+		Expect(u.Execute()).To(Equal(map[string]string{
+			"APPDYNAMICS_AGENT_UNIQUE_HOST_ID": "3c6f5e7a1d2b4c8e9f0a1b2c3d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5a6",
+		}))
+	})
+
+	it("reads container ID from Kubernetes cgroup v1", func() {
+		u.CgroupPath = filepath.Join("testdata", "unique-host-id", "cgroup-v1-kubernetes")
+
+		Expect(u.Execute()).To(Equal(map[string]string{
+			"APPDYNAMICS_AGENT_UNIQUE_HOST_ID": "0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9",
+		}))
+	})
+
+	it("reads container ID from cgroup v2 systemd scope", func() {
+		u.CgroupPath = filepath.Join("testdata", "unique-host-id", "cgroup-v2-systemd")
+
+		Expect(u.Execute()).To(Equal(map[string]string{
+			"APPDYNAMICS_AGENT_UNIQUE_HOST_ID": "0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f9",
+		}))
+	})
+
+	it("reads container ID from mountinfo with cgroup v2", func() {
+		u.MountInfoPath = filepath.Join("testdata", "unique-host-id", "mountinfo-v2")
+
+		id, source, err := u.ContainerID()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal("9f8e7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c5b4a39281706f5e4d3c2b1a0"))
+		Expect(source).To(Equal(u.MountInfoPath))
+	})
+
+	it("falls back to $HOSTNAME", func() {
+		u.CgroupPath = filepath.Join("testdata", "unique-host-id", "does-not-exist")
+
+		Expect(u.Execute()).To(Equal(map[string]string{
+			"APPDYNAMICS_AGENT_UNIQUE_HOST_ID": "test-hostname",
+		}))
+	})
+
+	it("passes unique host ID to the Java agent", func() {
+		t.Setenv("JAVA_TOOL_OPTIONS", "-javaagent:/layers/test/appdynamics-java/javaagent.jar")
+
+		Expect(u.Execute()).To(Equal(map[string]string{
+			"APPDYNAMICS_AGENT_UNIQUE_HOST_ID": "test-hostname",
+			"JAVA_TOOL_OPTIONS":                "-javaagent:/layers/test/appdynamics-java/javaagent.jar -Dappdynamics.agent.uniqueHostId=test-hostname",
+		}))
+	})
+
+	it("does not contribute for the PHP agent", func() {
+		t.Setenv("BPI_APPD_AGENT_PATH", "/layers/test/appdynamics-php")
+
+		buffer := bytes.NewBuffer(nil)
+		u.Logger = bard.NewLogger(buffer)
+
+		Expect(u.Execute()).To(BeNil())
+		Expect(buffer.String()).To(ContainSubstring("Skipping AppDynamics unique host ID, the PHP agent does not read $APPDYNAMICS_AGENT_UNIQUE_HOST_ID"))
+	})
+
+	it("does not override $APPDYNAMICS_AGENT_UNIQUE_HOST_ID", func() {
+		t.Setenv("APPDYNAMICS_AGENT_UNIQUE_HOST_ID", "test-unique-host-id")
+
+		Expect(u.Execute()).To(BeNil())
+	})
+
+	it("does not override -Dappdynamics.agent.uniqueHostId", func() {
+		t.Setenv("JAVA_TOOL_OPTIONS", "-Dappdynamics.agent.uniqueHostId=test-unique-host-id")
+
+		Expect(u.Execute()).To(BeNil())
+	})
+
+	it("does not contribute with $BPL_APPD_UNIQUE_HOST_ID=false", func() {
+		t.Setenv("BPL_APPD_UNIQUE_HOST_ID", "false")
+
+		Expect(u.Execute()).To(BeNil())
+	})
+
+	it("does not contribute if the agent is deactivated", func() {
+		t.Setenv("BPI_APPD_DEACTIVATE_UNCONFIGURED", "true")
+
+		Expect(u.Execute()).To(BeNil())
+
+		t.Setenv("APPDYNAMICS_CONTROLLER_HOST_NAME", "test-host")
+
+		Expect(u.Execute()).To(Equal(map[string]string{
+			"APPDYNAMICS_AGENT_UNIQUE_HOST_ID": "test-hostname",
+		}))
+	})
+
+	it("logs effective configuration with $BPL_APPD_DEBUG", func() {
+		t.Setenv("BPL_APPD_DEBUG", "true")
+		t.Setenv("APPDYNAMICS_AGENT_ACCOUNT_ACCESS_KEY", "test-access-key")
+		t.Setenv("JAVA_TOOL_OPTIONS", "-Dappdynamics.agent.accountAccessKey=test-access-key -Dappdynamics.agent.tierName=test-tier")
+
+		buffer := bytes.NewBuffer(nil)
+		u.Logger = bard.NewLogger(buffer)
+		u.Bindings = libcnb.Bindings{
+			{
+				Name:   "test-binding",
+				Type:   "AppDynamics",
+				Path:   "/test/binding",
+				Secret: map[string]string{"agent-account-access-key": "test-access-key"},
+			},
+		}
+
+		_, err := u.Execute()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(buffer.String()).To(ContainSubstring("Effective AppDynamics configuration:"))
+		Expect(buffer.String()).To(MatchRegexp(`APPDYNAMICS_AGENT_ACCOUNT_ACCESS_KEY\s+binding\s+\*{8}`))
+		Expect(buffer.String()).To(MatchRegexp(`APPDYNAMICS_AGENT_UNIQUE_HOST_ID\s+env\s+test-hostname`))
+		Expect(buffer.String()).To(MatchRegexp(`-Dappdynamics.agent.accountAccessKey\s+env\s+\*{8}`))
+		Expect(buffer.String()).To(MatchRegexp(`-Dappdynamics.agent.tierName\s+env\s+test-tier`))
+		Expect(buffer.String()).NotTo(ContainSubstring("test-access-key"))
+	})
+}